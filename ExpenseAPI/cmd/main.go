@@ -36,6 +36,59 @@ func main() {
 	router.HandleFunc("/expense", middlewares.AuthMiddleware(handlers.HandleGetExpenseRoute(channel))).Methods("GET")
 	router.HandleFunc("/expense", middlewares.AuthMiddleware(handlers.HandleUpdateExpenseRoute(channel))).Methods("PUT")
 	router.HandleFunc("/expense", middlewares.AuthMiddleware(handlers.HandleRemoveExpenseRoute(channel))).Methods("DELETE")
+	router.HandleFunc("/expense/batch", middlewares.AuthMiddleware(handlers.HandleBatchExpenseRoute(channel))).Methods("POST")
+	router.HandleFunc("/expense/export", middlewares.AuthMiddleware(handlers.HandleExportExpensesRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/import", middlewares.AuthMiddleware(handlers.HandleImportExpensesRoute(channel))).Methods("POST")
+	router.HandleFunc("/expense/report", middlewares.AuthMiddleware(handlers.HandleGetReportRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/forecast", middlewares.AuthMiddleware(handlers.HandleGetForecastRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/anomaly", middlewares.AuthMiddleware(handlers.HandleGetAnomalyRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/anomaly/acknowledge", middlewares.AuthMiddleware(handlers.HandleAcknowledgeAnomalyRoute(channel))).Methods("POST")
+	router.HandleFunc("/expense/trash", middlewares.AuthMiddleware(handlers.HandleGetTrashRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/trash/restore", middlewares.AuthMiddleware(handlers.HandleRestoreExpenseRoute(channel))).Methods("POST")
+	router.HandleFunc("/expense/cashflow", middlewares.AuthMiddleware(handlers.HandleGetCashFlowRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/recurring", middlewares.AuthMiddleware(handlers.HandleAddRecurringExpenseRoute(channel))).Methods("POST")
+	router.HandleFunc("/expense/recurring", middlewares.AuthMiddleware(handlers.HandleGetRecurringExpenseRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/recurring", middlewares.AuthMiddleware(handlers.HandleUpdateRecurringExpenseRoute(channel))).Methods("PUT")
+	router.HandleFunc("/expense/recurring", middlewares.AuthMiddleware(handlers.HandleRemoveRecurringExpenseRoute(channel))).Methods("DELETE")
+	router.HandleFunc("/expense/receipt", middlewares.AuthMiddleware(handlers.HandleAddReceiptRoute(channel))).Methods("POST")
+	router.HandleFunc("/expense/receipt", middlewares.AuthMiddleware(handlers.HandleGetReceiptRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/receipt", middlewares.AuthMiddleware(handlers.HandleRemoveReceiptRoute(channel))).Methods("DELETE")
+	// Registered after the other /expense routes, which would otherwise be taken for ids.
+	router.HandleFunc("/expense/{expenseId}", middlewares.AuthMiddleware(handlers.HandleGetExpenseByIdRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/{expenseId}", middlewares.AuthMiddleware(handlers.HandlePatchExpenseRoute(channel))).Methods("PATCH")
+	router.HandleFunc("/expense/{expenseId}", middlewares.AuthMiddleware(handlers.HandleRemoveExpenseByIdRoute(channel))).Methods("DELETE")
+	router.HandleFunc("/expense/{expenseId}/history", middlewares.AuthMiddleware(handlers.HandleGetExpenseHistoryRoute(channel))).Methods("GET")
+	router.HandleFunc("/expense/{expenseId}/revert", middlewares.AuthMiddleware(handlers.HandleRevertExpenseRoute(channel))).Methods("POST")
+	router.HandleFunc("/budget", middlewares.AuthMiddleware(handlers.HandleAddBudgetRoute(channel))).Methods("POST")
+	router.HandleFunc("/budget", middlewares.AuthMiddleware(handlers.HandleGetBudgetRoute(channel))).Methods("GET")
+	router.HandleFunc("/budget", middlewares.AuthMiddleware(handlers.HandleUpdateBudgetRoute(channel))).Methods("PUT")
+	router.HandleFunc("/budget", middlewares.AuthMiddleware(handlers.HandleRemoveBudgetRoute(channel))).Methods("DELETE")
+	router.HandleFunc("/budget/status", middlewares.AuthMiddleware(handlers.HandleGetBudgetStatusRoute(channel))).Methods("GET")
+	router.HandleFunc("/category", middlewares.AuthMiddleware(handlers.HandleAddCategoryRoute(channel))).Methods("POST")
+	router.HandleFunc("/category", middlewares.AuthMiddleware(handlers.HandleGetCategoryRoute(channel))).Methods("GET")
+	router.HandleFunc("/category", middlewares.AuthMiddleware(handlers.HandleUpdateCategoryRoute(channel))).Methods("PUT")
+	router.HandleFunc("/category", middlewares.AuthMiddleware(handlers.HandleRemoveCategoryRoute(channel))).Methods("DELETE")
+	router.HandleFunc("/category/merge", middlewares.AuthMiddleware(handlers.HandleMergeCategoryRoute(channel))).Methods("POST")
+	router.HandleFunc("/rule", middlewares.AuthMiddleware(handlers.HandleAddRuleRoute(channel))).Methods("POST")
+	router.HandleFunc("/rule", middlewares.AuthMiddleware(handlers.HandleGetRuleRoute(channel))).Methods("GET")
+	router.HandleFunc("/rule", middlewares.AuthMiddleware(handlers.HandleUpdateRuleRoute(channel))).Methods("PUT")
+	router.HandleFunc("/rule", middlewares.AuthMiddleware(handlers.HandleRemoveRuleRoute(channel))).Methods("DELETE")
+	router.HandleFunc("/rule/apply", middlewares.AuthMiddleware(handlers.HandleApplyRulesRoute(channel))).Methods("POST")
+	router.HandleFunc("/account", middlewares.AuthMiddleware(handlers.HandleAddAccountRoute(channel))).Methods("POST")
+	router.HandleFunc("/account", middlewares.AuthMiddleware(handlers.HandleGetAccountRoute(channel))).Methods("GET")
+	router.HandleFunc("/account", middlewares.AuthMiddleware(handlers.HandleUpdateAccountRoute(channel))).Methods("PUT")
+	router.HandleFunc("/account", middlewares.AuthMiddleware(handlers.HandleRemoveAccountRoute(channel))).Methods("DELETE")
+	router.HandleFunc("/account/balance", middlewares.AuthMiddleware(handlers.HandleGetAccountBalanceRoute(channel))).Methods("GET")
+	router.HandleFunc("/group", middlewares.AuthMiddleware(handlers.HandleAddGroupRoute(channel))).Methods("POST")
+	router.HandleFunc("/group", middlewares.AuthMiddleware(handlers.HandleGetGroupRoute(channel))).Methods("GET")
+	router.HandleFunc("/group", middlewares.AuthMiddleware(handlers.HandleUpdateGroupRoute(channel))).Methods("PUT")
+	router.HandleFunc("/group", middlewares.AuthMiddleware(handlers.HandleRemoveGroupRoute(channel))).Methods("DELETE")
+	router.HandleFunc("/group/expense", middlewares.AuthMiddleware(handlers.HandleAddGroupExpenseRoute(channel))).Methods("POST")
+	router.HandleFunc("/group/expense", middlewares.AuthMiddleware(handlers.HandleGetGroupExpenseRoute(channel))).Methods("GET")
+	router.HandleFunc("/group/expense", middlewares.AuthMiddleware(handlers.HandleRemoveGroupExpenseRoute(channel))).Methods("DELETE")
+	router.HandleFunc("/group/balance", middlewares.AuthMiddleware(handlers.HandleGetGroupBalanceRoute(channel))).Methods("GET")
+	router.HandleFunc("/group/settlement", middlewares.AuthMiddleware(handlers.HandleAddSettlementRoute(channel))).Methods("POST")
+	router.HandleFunc("/group/settlement", middlewares.AuthMiddleware(handlers.HandleGetSettlementRoute(channel))).Methods("GET")
 
 	expenseService, err := services.NewExpenseService(connection, mongoURI, databaseName, "expenses")
 	if err != nil {
@@ -43,6 +96,12 @@ func main() {
 	}
 	go expenseService.Start()
 
+	groupService, err := services.NewGroupService(connection, mongoURI, databaseName, "groups")
+	if err != nil {
+		log.Fatalf("Failed to initialize group service: %v", err)
+	}
+	go groupService.Start()
+
 	stopChannel := make(chan os.Signal, 1)
 	signal.Notify(stopChannel, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
@@ -52,4 +111,5 @@ func main() {
 
 	<-stopChannel
 	expenseService.Stop()
+	groupService.Stop()
 }
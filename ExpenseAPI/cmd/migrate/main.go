@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"expense/internal/money"
+	"expense/internal/repositories"
+	"log"
+	"os"
+)
+
+// Converts expenses stored with floating point amounts into integer minor units.
+// Documents that already hold integer amounts are left untouched, so the
+// migration can be run more than once.
+func main() {
+	mongoURI := os.Getenv("MONGO_URI")
+	databaseName := os.Getenv("DATABASE_NAME")
+
+	repo, err := repositories.NewMongoDBRepository(mongoURI, databaseName, "expenses")
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer repo.Close(context.Background())
+
+	ctx := context.Background()
+	filter := map[string]interface{}{"amount": map[string]interface{}{"$type": "double"}}
+	result, err := repo.Find(ctx, filter)
+	if !result.Success {
+		log.Fatalf("Failed to find expenses: %v", err)
+	}
+
+	migrated := 0
+	for _, expense := range result.Data {
+		expenseId, _ := expense["expenseId"].(string)
+		amount, _ := expense["amount"].(float64)
+		currency, _ := expense["currency"].(string)
+		if currency == "" {
+			currency = money.DefaultCurrency()
+		}
+
+		minor, err := money.FromFloat(amount, currency)
+		if err != nil {
+			log.Printf("Skipping expense (%s): %v", expenseId, err)
+			continue
+		}
+
+		update := map[string]interface{}{
+			"$set": map[string]interface{}{
+				"amount":   minor,
+				"currency": currency,
+			},
+		}
+		result, err := repo.Update(ctx, map[string]interface{}{"expenseId": expenseId}, update)
+		if !result.Success {
+			log.Printf("Failed to migrate expense (%s): %v", expenseId, err)
+			continue
+		}
+		migrated++
+	}
+
+	log.Printf("Migrated %d of %d expenses.", migrated, len(result.Data))
+}
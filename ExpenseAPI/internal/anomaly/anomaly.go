@@ -0,0 +1,68 @@
+// Package anomaly scores amounts against the history of similar amounts to
+// catch mistyped or fraudulent entries.
+package anomaly
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// MinHistory is the number of prior amounts needed before anything is
+	// flagged; smaller histories are too noisy to judge.
+	MinHistory = 8
+	// Threshold is the robust z-score above which an amount is flagged.
+	Threshold = 3.5
+	// minSpread keeps the spread from collapsing to zero when all prior
+	// amounts are equal, e.g. for subscriptions. It is a share of the median.
+	minSpread = 0.05
+)
+
+// Result is the verdict on an amount.
+type Result struct {
+	Score     float64
+	Median    int64
+	Flagged   bool
+	ExtraZero bool
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return (sorted[middle-1] + sorted[middle]) / 2
+}
+
+// Check scores the amount with the robust z-score 0.6745 * (x - median) / MAD,
+// where MAD is the median absolute deviation of the history. Unlike the mean
+// and standard deviation, both are hardly moved by earlier outliers.
+func Check(history []int64, amount int64) Result {
+	if len(history) < MinHistory {
+		return Result{}
+	}
+	values := make([]float64, len(history))
+	for i, value := range history {
+		values[i] = float64(value)
+	}
+	center := median(values)
+	deviations := make([]float64, len(values))
+	for i, value := range values {
+		deviations[i] = math.Abs(value - center)
+	}
+	spread := math.Max(median(deviations), math.Max(math.Abs(center)*minSpread, 1))
+
+	score := 0.6745 * (float64(amount) - center) / spread
+	result := Result{
+		Score:   math.Round(score*100) / 100,
+		Median:  int64(math.Round(center)),
+		Flagged: math.Abs(score) > Threshold,
+	}
+	if result.Flagged && center > 0 {
+		ratio := float64(amount) / center
+		result.ExtraZero = ratio > 9.5 && ratio < 10.5
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+// escapeFormula keeps spreadsheets from running text cells as formulas, by
+// prefixing cells that start like one with an apostrophe.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (c *csvWriter) Write(record Record) error {
+	return c.writer.Write([]string{
+		record.Date.Format("2006-01-02"),
+		record.Type,
+		escapeFormula(record.Description),
+		escapeFormula(record.Payee),
+		escapeFormula(record.Notes),
+		record.Amount,
+		record.Currency,
+		escapeFormula(record.Category),
+		escapeFormula(strings.Join(record.Tags, ";")),
+		record.AccountId,
+		record.ToAccountId,
+		record.BaseAmount,
+		record.BaseCurrency,
+		record.ExpenseId,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &ndjsonWriter{encoder: encoder}
+}
+
+// Write encodes the record on a line of its own; the encoder ends every value with a newline.
+func (n *ndjsonWriter) Write(record Record) error {
+	if record.Tags == nil {
+		record.Tags = []string{}
+	}
+	return n.encoder.Encode(record)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,55 @@
+package exporter
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// Record is an exported entry. Amounts are decimal strings in their currency.
+type Record struct {
+	ExpenseId    string    `json:"expenseId"`
+	Date         time.Time `json:"date"`
+	Type         string    `json:"type"`
+	Description  string    `json:"description"`
+	Payee        string    `json:"payee"`
+	Notes        string    `json:"notes"`
+	Amount       string    `json:"amount"`
+	Currency     string    `json:"currency"`
+	Category     string    `json:"category"`
+	Tags         []string  `json:"tags"`
+	AccountId    string    `json:"accountId"`
+	ToAccountId  string    `json:"toAccountId"`
+	BaseAmount   string    `json:"baseAmount"`
+	BaseCurrency string    `json:"baseCurrency"`
+}
+
+var columns = []string{
+	"Date", "Type", "Description", "Payee", "Notes", "Amount", "Currency", "Category",
+	"Tags", "Account", "To Account", "Base Amount", "Base Currency", "Expense Id",
+}
+
+// Writer writes records one at a time, so an export never has to be held in
+// memory. Close completes the output and must be called after the last record.
+type Writer interface {
+	Write(record Record) error
+	Close() error
+}
+
+var ContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+	"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w)
+	case "ndjson":
+		return newNDJSONWriter(w), nil
+	case "xlsx":
+		return newXLSXWriter(w)
+	}
+	return nil, errors.New("Format is unknown!")
+}
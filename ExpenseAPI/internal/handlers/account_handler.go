@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense/internal/middlewares"
+	"expense/internal/money"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+var accountTypes = map[string]bool{
+	"cash":        true,
+	"bank":        true,
+	"debit_card":  true,
+	"credit_card": true,
+	"other":       true,
+}
+
+// normalizeAccount validates the type, currency and opening balance of an
+// account. Unlike entry amounts, the opening balance may be zero or negative,
+// e.g. for a credit card that is already in use.
+func normalizeAccount(r *http.Request, accountType string, currency string, openingBalance json.Number) (string, string, json.Number, error) {
+	accountType = strings.ToLower(accountType)
+	if accountType == "" {
+		accountType = "bank"
+	}
+	if !accountTypes[accountType] {
+		return "", "", "", errors.New("\"Type\" must be one of \"cash\", \"bank\", \"debit_card\", \"credit_card\" or \"other\"!")
+	}
+	if currency == "" {
+		currency = middlewares.GetBaseCurrencyFromRequest(r)
+	}
+	currency = strings.ToUpper(currency)
+	if !money.IsCurrency(currency) {
+		return "", "", "", errors.New("\"Currency\" must be an ISO 4217 currency code!")
+	}
+	if openingBalance == "" {
+		openingBalance = "0"
+	}
+	minor, err := money.Parse(openingBalance.String(), currency)
+	if err != nil {
+		return "", "", "", err
+	}
+	return accountType, currency, json.Number(money.Format(minor, currency)), nil
+}
+
+type getAccountRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	AccountId	string	`json:"accountId"`
+}
+
+type getAccountResponse struct {
+	Message		string		`json:"message"`
+	Success		bool		`json:"success"`
+	Accounts	interface{}	`json:"accounts"`
+}
+
+func HandleGetAccountRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getAccountRequestData := &getAccountRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "accountId" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getAccountRequestData.AccountId = query.Get("accountId")
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getAccountRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetAccount", getAccountRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getAccountResponseData := getAccountResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Accounts: data["accounts"],
+					}
+
+					getAccountResponseDataJSON, err := json.Marshal(getAccountResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getAccountResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type addAccountRequest struct {
+	Action			string		`json:"action"`
+	UserId			string		`json:"userId"`
+	Name			string		`json:"name"`
+	Type			string		`json:"type"`
+	Currency		string		`json:"currency"`
+	OpeningBalance	json.Number	`json:"openingBalance"`
+}
+
+type addAccountResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	AccountId	string	`json:"accountId"`
+}
+
+func HandleAddAccountRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addAccountRequestData := &addAccountRequest{}
+		err := json.NewDecoder(r.Body).Decode(&addAccountRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		addAccountRequestData.Name = strings.TrimSpace(addAccountRequestData.Name)
+		if addAccountRequestData.Name == "" {
+			http.Error(w, "\"Name\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		addAccountRequestData.Type, addAccountRequestData.Currency, addAccountRequestData.OpeningBalance, err = normalizeAccount(r, addAccountRequestData.Type, addAccountRequestData.Currency, addAccountRequestData.OpeningBalance)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		addAccountRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "AddAccount", addAccountRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					accountId, _ := data["accountId"].(string)
+
+					addAccountResponseData := addAccountResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						AccountId: accountId,
+					}
+
+					addAccountResponseDataJSON, err := json.Marshal(addAccountResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(addAccountResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type updateAccountRequest struct {
+	Action			string		`json:"action"`
+	UserId			string		`json:"userId"`
+	AccountId		string		`json:"accountId"`
+	Name			string		`json:"name"`
+	Type			string		`json:"type"`
+	Currency		string		`json:"currency"`
+	OpeningBalance	json.Number	`json:"openingBalance"`
+}
+
+type updateAccountResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleUpdateAccountRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		updateAccountRequestData := &updateAccountRequest{}
+		err := json.NewDecoder(r.Body).Decode(&updateAccountRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		updateAccountRequestData.Name = strings.TrimSpace(updateAccountRequestData.Name)
+		if updateAccountRequestData.AccountId == "" || updateAccountRequestData.Name == "" {
+			http.Error(w, "\"AccountId\" and \"Name\" are required!", http.StatusBadRequest)
+			return
+		}
+
+		updateAccountRequestData.Type, updateAccountRequestData.Currency, updateAccountRequestData.OpeningBalance, err = normalizeAccount(r, updateAccountRequestData.Type, updateAccountRequestData.Currency, updateAccountRequestData.OpeningBalance)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		updateAccountRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "UpdateAccount", updateAccountRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					updateAccountResponseData := updateAccountResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					updateAccountResponseDataJSON, err := json.Marshal(updateAccountResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(updateAccountResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type removeAccountRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	AccountId	string	`json:"accountId"`
+}
+
+type removeAccountResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRemoveAccountRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		removeAccountRequestData := &removeAccountRequest{}
+		err := json.NewDecoder(r.Body).Decode(&removeAccountRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if removeAccountRequestData.AccountId == "" {
+			http.Error(w, "\"AccountId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		removeAccountRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "RemoveAccount", removeAccountRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					removeAccountResponseData := removeAccountResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					removeAccountResponseDataJSON, err := json.Marshal(removeAccountResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(removeAccountResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type getAccountBalanceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	AccountId	string	`json:"accountId"`
+	From		string	`json:"from,omitempty"`
+	To			string	`json:"to,omitempty"`
+}
+
+type getAccountBalanceResponse struct {
+	Message		string		`json:"message"`
+	Success		bool		`json:"success"`
+	Accounts	interface{}	`json:"accounts"`
+}
+
+func HandleGetAccountBalanceRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getAccountBalanceRequestData := &getAccountBalanceRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "accountId" && key != "from" && key != "to" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getAccountBalanceRequestData.AccountId = query.Get("accountId")
+
+		from, to, err := ParseDateRange(query.Get("from"), query.Get("to"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		getAccountBalanceRequestData.From = from
+		getAccountBalanceRequestData.To = to
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getAccountBalanceRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetAccountBalance", getAccountBalanceRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getAccountBalanceResponseData := getAccountBalanceResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Accounts: data["accounts"],
+					}
+
+					getAccountBalanceResponseDataJSON, err := json.Marshal(getAccountBalanceResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getAccountBalanceResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
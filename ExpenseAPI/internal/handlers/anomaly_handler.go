@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense/internal/middlewares"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+type getAnomalyRequest struct {
+	Action			string	`json:"action"`
+	UserId			string	`json:"userId"`
+	Acknowledged	bool	`json:"acknowledged"`
+	BaseCurrency	string	`json:"baseCurrency"`
+}
+
+type getAnomalyResponse struct {
+	Message		string		`json:"message"`
+	Success		bool		`json:"success"`
+	Expenses	interface{}	`json:"expenses"`
+}
+
+func HandleGetAnomalyRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getAnomalyRequestData := &getAnomalyRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "acknowledged" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		if acknowledged := query.Get("acknowledged"); acknowledged != "" {
+			parsedAcknowledged, err := strconv.ParseBool(acknowledged)
+			if err != nil {
+				http.Error(w, "\"Acknowledged\" must be either \"true\" or \"false\"!", http.StatusBadRequest)
+				return
+			}
+			getAnomalyRequestData.Acknowledged = parsedAcknowledged
+		}
+		getAnomalyRequestData.BaseCurrency = middlewares.GetBaseCurrencyFromRequest(r)
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getAnomalyRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetAnomaly", getAnomalyRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getAnomalyResponseData := getAnomalyResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Expenses: data["expenses"],
+					}
+
+					getAnomalyResponseDataJSON, err := json.Marshal(getAnomalyResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getAnomalyResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type acknowledgeAnomalyRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+}
+
+type acknowledgeAnomalyResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleAcknowledgeAnomalyRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		acknowledgeAnomalyRequestData := &acknowledgeAnomalyRequest{}
+		err := json.NewDecoder(r.Body).Decode(&acknowledgeAnomalyRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if acknowledgeAnomalyRequestData.ExpenseId == "" {
+			http.Error(w, "\"ExpenseId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		acknowledgeAnomalyRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "AcknowledgeAnomaly", acknowledgeAnomalyRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					acknowledgeAnomalyResponseData := acknowledgeAnomalyResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					acknowledgeAnomalyResponseDataJSON, err := json.Marshal(acknowledgeAnomalyResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(acknowledgeAnomalyResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
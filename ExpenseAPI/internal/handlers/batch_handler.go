@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense/internal/middlewares"
+	"net/http"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+const maxBatchOperations = 100
+
+type batchOperationRequest struct {
+	Index		int						`json:"index"`
+	Op			string					`json:"op"`
+	Create		*addExpenseRequest		`json:"create,omitempty"`
+	Update		*updateExpenseRequest	`json:"update,omitempty"`
+	ExpenseId	string					`json:"expenseId,omitempty"`
+	Version		*int64					`json:"version,omitempty"`
+}
+
+type batchExpenseRequest struct {
+	Action		string					`json:"action"`
+	UserId		string					`json:"userId"`
+	Atomic		bool					`json:"atomic"`
+	Operations	[]batchOperationRequest	`json:"operations"`
+}
+
+type batchExpenseResponse struct {
+	Message	string					`json:"message"`
+	Success	bool					`json:"success"`
+	Results	[]map[string]interface{}	`json:"results"`
+}
+
+// errVersionRequired stands in for a missing If-Match header, which a batch
+// cannot send per operation.
+var errVersionRequired = errors.New("\"Version\" of the expense is required!")
+
+// parseBatchOperation reads an operation of a batch: "create" takes the body of
+// POST /expense, "update" the body of PUT /expense and "delete" an "expenseId".
+// Updates and deletes name the "version" they are based on, like the ETag of
+// an If-Match header.
+func parseBatchOperation(index int, raw json.RawMessage) (batchOperationRequest, error) {
+	operation := batchOperationRequest{}
+	err := json.Unmarshal(raw, &operation)
+	operation.Index = index
+	if err != nil {
+		return operation, errors.New("Format is invalid!")
+	}
+	switch operation.Op {
+	case "create":
+		operation.Create = &addExpenseRequest{}
+		err = json.Unmarshal(raw, operation.Create)
+		if err != nil {
+			return operation, errors.New("Format is invalid!")
+		}
+		return operation, normalizeAddExpense(operation.Create)
+	case "update":
+		operation.Update = &updateExpenseRequest{}
+		err = json.Unmarshal(raw, operation.Update)
+		if err != nil {
+			return operation, errors.New("Format is invalid!")
+		}
+		if operation.Update.ExpenseId == "" || operation.Update.Description == "" || operation.Update.Amount == "" {
+			return operation, errors.New("\"ExpenseId\", \"Description\" and \"Amount\" are required!")
+		}
+		if operation.Update.Version == nil {
+			return operation, errVersionRequired
+		}
+		return operation, normalizeUpdateExpense(operation.Update)
+	case "delete":
+		if operation.ExpenseId == "" {
+			return operation, errors.New("\"ExpenseId\" is required!")
+		}
+		if operation.Version == nil {
+			return operation, errVersionRequired
+		}
+		return operation, nil
+	}
+	return operation, errors.New("\"Op\" must be one of \"create\", \"update\" or \"delete\"!")
+}
+
+// batchResults merges the results of the service with the operations rejected
+// before, in the order of the request.
+func batchResults(data map[string]interface{}, rejected []map[string]interface{}) []map[string]interface{} {
+	results := make([]map[string]interface{}, 0)
+	serviceResults, _ := data["results"].([]interface{})
+	for _, eachResult := range serviceResults {
+		if result, ok := eachResult.(map[string]interface{}); ok {
+			results = append(results, result)
+		}
+	}
+	results = append(results, rejected...)
+	sort.SliceStable(results, func(i, j int) bool {
+		first, _ := results[i]["index"].(float64)
+		second, _ := results[j]["index"].(float64)
+		return first < second
+	})
+	return results
+}
+
+func writeBatchResponse(w http.ResponseWriter, status int, batchExpenseResponseData batchExpenseResponse) {
+	batchExpenseResponseDataJSON, err := json.Marshal(batchExpenseResponseData)
+	if err != nil {
+		http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(batchExpenseResponseDataJSON)
+}
+
+func HandleBatchExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Atomic		bool				`json:"atomic"`
+			Operations	[]json.RawMessage	`json:"operations"`
+		}{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+		if len(body.Operations) == 0 || len(body.Operations) > maxBatchOperations {
+			http.Error(w, "\"Operations\" must contain between 1 and 100 operations!", http.StatusBadRequest)
+			return
+		}
+
+		// Invalid operations are rejected here; in an atomic batch they stop the
+		// whole batch before anything is sent.
+		batchExpenseRequestData := &batchExpenseRequest{Atomic: body.Atomic}
+		rejected := make([]map[string]interface{}, 0)
+		for index, raw := range body.Operations {
+			operation, err := parseBatchOperation(index, raw)
+			if err != nil {
+				status := http.StatusBadRequest
+				if err == errVersionRequired {
+					status = http.StatusPreconditionRequired
+				}
+				rejected = append(rejected, map[string]interface{}{
+					"index":   float64(index),
+					"op":      operation.Op,
+					"success": false,
+					"status":  status,
+					"message": err.Error(),
+				})
+				continue
+			}
+			batchExpenseRequestData.Operations = append(batchExpenseRequestData.Operations, operation)
+		}
+		if len(rejected) != 0 && (body.Atomic || len(batchExpenseRequestData.Operations) == 0) {
+			results := rejected
+			if body.Atomic {
+				for _, operation := range batchExpenseRequestData.Operations {
+					results = append(results, map[string]interface{}{
+						"index":   float64(operation.Index),
+						"op":      operation.Op,
+						"success": false,
+						"status":  http.StatusFailedDependency,
+						"message": "Operation is not applied!",
+					})
+				}
+			}
+			writeBatchResponse(w, http.StatusBadRequest, batchExpenseResponse{
+				Message: "Batch is rejected, no changes were made!",
+				Success: false,
+				Results: batchResults(nil, results),
+			})
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		batchExpenseRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "BatchExpense", batchExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					writeBatchResponse(w, http.StatusOK, batchExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Results: batchResults(data, rejected),
+					})
+				} else {
+					status, _ := data["status"].(float64)
+					if _, exists := data["results"]; !exists || status == 0 {
+						WriteServiceError(w, data)
+						return
+					}
+					message, _ := data["message"].(string)
+					writeBatchResponse(w, int(status), batchExpenseResponse{
+						Message: message,
+						Success: false,
+						Results: batchResults(data, nil),
+					})
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
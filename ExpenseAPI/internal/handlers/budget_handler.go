@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense/internal/middlewares"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+var budgetPeriods = map[string]bool{
+	"weekly":  true,
+	"monthly": true,
+}
+
+type getBudgetRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	BudgetId	string	`json:"budgetId"`
+}
+
+type getBudgetResponse struct {
+	Message	string		`json:"message"`
+	Success	bool		`json:"success"`
+	Budgets	interface{}	`json:"budgets"`
+}
+
+func HandleGetBudgetRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getBudgetRequestData := &getBudgetRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "budgetId" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getBudgetRequestData.BudgetId = query.Get("budgetId")
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getBudgetRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetBudget", getBudgetRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getBudgetResponseData := getBudgetResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Budgets: data["budgets"],
+					}
+
+					getBudgetResponseDataJSON, err := json.Marshal(getBudgetResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getBudgetResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type addBudgetRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	Category	string		`json:"category"`
+	Amount		json.Number	`json:"amount"`
+	Currency	string		`json:"currency"`
+	Period		string		`json:"period"`
+}
+
+type addBudgetResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	BudgetId	string	`json:"budgetId"`
+}
+
+func HandleAddBudgetRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addBudgetRequestData := &addBudgetRequest{}
+		err := json.NewDecoder(r.Body).Decode(&addBudgetRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if addBudgetRequestData.Category == "" || addBudgetRequestData.Amount == "" || addBudgetRequestData.Period == "" {
+			http.Error(w, "\"Category\", \"Amount\" and \"Period\" are required!", http.StatusBadRequest)
+			return
+		}
+
+		addBudgetRequestData.Period = strings.ToLower(addBudgetRequestData.Period)
+		if !budgetPeriods[addBudgetRequestData.Period] {
+			http.Error(w, "\"Period\" must be either \"weekly\" or \"monthly\"!", http.StatusBadRequest)
+			return
+		}
+
+		addBudgetRequestData.Amount, addBudgetRequestData.Currency, err = NormalizeMoney(addBudgetRequestData.Amount, addBudgetRequestData.Currency)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.HasPrefix(addBudgetRequestData.Amount.String(), "-") {
+			http.Error(w, "\"Amount\" must be positive!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		addBudgetRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "AddBudget", addBudgetRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					budgetId, _ := data["budgetId"].(string)
+
+					addBudgetResponseData := addBudgetResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						BudgetId: budgetId,
+					}
+
+					addBudgetResponseDataJSON, err := json.Marshal(addBudgetResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(addBudgetResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type updateBudgetRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	BudgetId	string		`json:"budgetId"`
+	Category	string		`json:"category"`
+	Amount		json.Number	`json:"amount"`
+	Currency	string		`json:"currency"`
+	Period		string		`json:"period"`
+}
+
+type updateBudgetResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleUpdateBudgetRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		updateBudgetRequestData := &updateBudgetRequest{}
+		err := json.NewDecoder(r.Body).Decode(&updateBudgetRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if updateBudgetRequestData.BudgetId == "" || updateBudgetRequestData.Category == "" || updateBudgetRequestData.Amount == "" || updateBudgetRequestData.Period == "" {
+			http.Error(w, "\"BudgetId\", \"Category\", \"Amount\" and \"Period\" are required!", http.StatusBadRequest)
+			return
+		}
+
+		updateBudgetRequestData.Period = strings.ToLower(updateBudgetRequestData.Period)
+		if !budgetPeriods[updateBudgetRequestData.Period] {
+			http.Error(w, "\"Period\" must be either \"weekly\" or \"monthly\"!", http.StatusBadRequest)
+			return
+		}
+
+		updateBudgetRequestData.Amount, updateBudgetRequestData.Currency, err = NormalizeMoney(updateBudgetRequestData.Amount, updateBudgetRequestData.Currency)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.HasPrefix(updateBudgetRequestData.Amount.String(), "-") {
+			http.Error(w, "\"Amount\" must be positive!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		updateBudgetRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "UpdateBudget", updateBudgetRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					updateBudgetResponseData := updateBudgetResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					updateBudgetResponseDataJSON, err := json.Marshal(updateBudgetResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(updateBudgetResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type removeBudgetRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	BudgetId	string	`json:"budgetId"`
+}
+
+type removeBudgetResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRemoveBudgetRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		removeBudgetRequestData := &removeBudgetRequest{}
+		err := json.NewDecoder(r.Body).Decode(&removeBudgetRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if removeBudgetRequestData.BudgetId == "" {
+			http.Error(w, "\"BudgetId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		removeBudgetRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "RemoveBudget", removeBudgetRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					removeBudgetResponseData := removeBudgetResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					removeBudgetResponseDataJSON, err := json.Marshal(removeBudgetResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(removeBudgetResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type getBudgetStatusRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	BudgetId	string	`json:"budgetId"`
+	Date		string	`json:"date,omitempty"`
+}
+
+type getBudgetStatusResponse struct {
+	Message	string		`json:"message"`
+	Success	bool		`json:"success"`
+	Budgets	interface{}	`json:"budgets"`
+}
+
+func HandleGetBudgetStatusRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getBudgetStatusRequestData := &getBudgetStatusRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "budgetId" && key != "date" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getBudgetStatusRequestData.BudgetId = query.Get("budgetId")
+		if date := query.Get("date"); date != "" {
+			parsedDate, _, err := ParseDate(date)
+			if err != nil {
+				http.Error(w, "\"Date\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!", http.StatusBadRequest)
+				return
+			}
+			getBudgetStatusRequestData.Date = parsedDate.Format(time.RFC3339)
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getBudgetStatusRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetBudgetStatus", getBudgetStatusRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getBudgetStatusResponseData := getBudgetStatusResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Budgets: data["budgets"],
+					}
+
+					getBudgetStatusResponseDataJSON, err := json.Marshal(getBudgetStatusResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getBudgetStatusResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense/internal/middlewares"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+var cashFlowPeriods = map[string]bool{
+	"weekly":  true,
+	"monthly": true,
+	"yearly":  true,
+}
+
+type getCashFlowRequest struct {
+	Action			string	`json:"action"`
+	UserId			string	`json:"userId"`
+	From			string	`json:"from,omitempty"`
+	To				string	`json:"to,omitempty"`
+	Period			string	`json:"period"`
+	BaseCurrency	string	`json:"baseCurrency"`
+}
+
+type getCashFlowResponse struct {
+	Message			string		`json:"message"`
+	Success			bool		`json:"success"`
+	BaseCurrency	string		`json:"baseCurrency"`
+	Periods			interface{}	`json:"periods"`
+	Income			string		`json:"income"`
+	Expenses		string		`json:"expenses"`
+	Net				string		`json:"net"`
+	MissingRates	bool		`json:"missingRates"`
+}
+
+func HandleGetCashFlowRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getCashFlowRequestData := &getCashFlowRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "from" && key != "to" && key != "period" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+
+		from, to, err := ParseDateRange(query.Get("from"), query.Get("to"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		getCashFlowRequestData.From = from
+		getCashFlowRequestData.To = to
+
+		getCashFlowRequestData.Period = query.Get("period")
+		if getCashFlowRequestData.Period == "" {
+			getCashFlowRequestData.Period = "monthly"
+		}
+		if !cashFlowPeriods[getCashFlowRequestData.Period] {
+			http.Error(w, "\"Period\" must be one of \"weekly\", \"monthly\" or \"yearly\"!", http.StatusBadRequest)
+			return
+		}
+		getCashFlowRequestData.BaseCurrency = middlewares.GetBaseCurrencyFromRequest(r)
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getCashFlowRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetCashFlow", getCashFlowRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					baseCurrency, _ := data["baseCurrency"].(string)
+					income, _ := data["income"].(string)
+					expenses, _ := data["expenses"].(string)
+					net, _ := data["net"].(string)
+					missingRates, _ := data["missingRates"].(bool)
+
+					getCashFlowResponseData := getCashFlowResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						BaseCurrency: baseCurrency,
+						Periods: data["periods"],
+						Income: income,
+						Expenses: expenses,
+						Net: net,
+						MissingRates: missingRates,
+					}
+
+					getCashFlowResponseDataJSON, err := json.Marshal(getCashFlowResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getCashFlowResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
@@ -0,0 +1,500 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense/internal/middlewares"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+var colorPattern = regexp.MustCompile("^#[0-9A-Fa-f]{6}$")
+
+func validateCategoryMetadata(name string, color string, icon string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("\"Name\" is required!")
+	}
+	if len(name) > 64 {
+		return errors.New("\"Name\" must be at most 64 characters long!")
+	}
+	if color != "" && !colorPattern.MatchString(color) {
+		return errors.New("\"Color\" must be a hex color such as #1A2B3C!")
+	}
+	if len(icon) > 64 {
+		return errors.New("\"Icon\" must be at most 64 characters long!")
+	}
+	return nil
+}
+
+type getCategoryRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+}
+
+type getCategoryResponse struct {
+	Message		string		`json:"message"`
+	Success		bool		`json:"success"`
+	Categories	interface{}	`json:"categories"`
+}
+
+func HandleGetCategoryRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getCategoryRequestData := &getCategoryRequest{}
+
+		if len(r.URL.Query()) != 0 {
+			http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getCategoryRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetCategory", getCategoryRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getCategoryResponseData := getCategoryResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Categories: data["categories"],
+					}
+
+					getCategoryResponseDataJSON, err := json.Marshal(getCategoryResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getCategoryResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type addCategoryRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	Name		string	`json:"name"`
+	ParentId	string	`json:"parentId"`
+	Color		string	`json:"color"`
+	Icon		string	`json:"icon"`
+}
+
+type addCategoryResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	CategoryId	string	`json:"categoryId"`
+}
+
+func HandleAddCategoryRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addCategoryRequestData := &addCategoryRequest{}
+		err := json.NewDecoder(r.Body).Decode(&addCategoryRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		addCategoryRequestData.Name = strings.TrimSpace(addCategoryRequestData.Name)
+		if err := validateCategoryMetadata(addCategoryRequestData.Name, addCategoryRequestData.Color, addCategoryRequestData.Icon); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		addCategoryRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "AddCategory", addCategoryRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					categoryId, _ := data["categoryId"].(string)
+
+					addCategoryResponseData := addCategoryResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						CategoryId: categoryId,
+					}
+
+					addCategoryResponseDataJSON, err := json.Marshal(addCategoryResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(addCategoryResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type updateCategoryRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	CategoryId	string	`json:"categoryId"`
+	Name		string	`json:"name"`
+	ParentId	string	`json:"parentId"`
+	Color		string	`json:"color"`
+	Icon		string	`json:"icon"`
+}
+
+type updateCategoryResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleUpdateCategoryRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		updateCategoryRequestData := &updateCategoryRequest{}
+		err := json.NewDecoder(r.Body).Decode(&updateCategoryRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if updateCategoryRequestData.CategoryId == "" {
+			http.Error(w, "\"CategoryId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		updateCategoryRequestData.Name = strings.TrimSpace(updateCategoryRequestData.Name)
+		if err := validateCategoryMetadata(updateCategoryRequestData.Name, updateCategoryRequestData.Color, updateCategoryRequestData.Icon); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		updateCategoryRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "UpdateCategory", updateCategoryRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					updateCategoryResponseData := updateCategoryResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					updateCategoryResponseDataJSON, err := json.Marshal(updateCategoryResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(updateCategoryResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type removeCategoryRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	CategoryId	string	`json:"categoryId"`
+}
+
+type removeCategoryResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRemoveCategoryRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		removeCategoryRequestData := &removeCategoryRequest{}
+		err := json.NewDecoder(r.Body).Decode(&removeCategoryRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if removeCategoryRequestData.CategoryId == "" {
+			http.Error(w, "\"CategoryId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		removeCategoryRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "RemoveCategory", removeCategoryRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					removeCategoryResponseData := removeCategoryResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					removeCategoryResponseDataJSON, err := json.Marshal(removeCategoryResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(removeCategoryResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type mergeCategoryRequest struct {
+	Action				string	`json:"action"`
+	UserId				string	`json:"userId"`
+	SourceCategoryId	string	`json:"sourceCategoryId"`
+	TargetCategoryId	string	`json:"targetCategoryId"`
+}
+
+type mergeCategoryResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleMergeCategoryRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mergeCategoryRequestData := &mergeCategoryRequest{}
+		err := json.NewDecoder(r.Body).Decode(&mergeCategoryRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if mergeCategoryRequestData.SourceCategoryId == "" || mergeCategoryRequestData.TargetCategoryId == "" {
+			http.Error(w, "\"SourceCategoryId\" and \"TargetCategoryId\" are required!", http.StatusBadRequest)
+			return
+		}
+		if mergeCategoryRequestData.SourceCategoryId == mergeCategoryRequestData.TargetCategoryId {
+			http.Error(w, "A category cannot be merged into itself!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		mergeCategoryRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "MergeCategory", mergeCategoryRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					mergeCategoryResponseData := mergeCategoryResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					mergeCategoryResponseDataJSON, err := json.Marshal(mergeCategoryResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(mergeCategoryResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
@@ -2,8 +2,14 @@ package handlers
 
 import (
 	"expense/internal/middlewares"
+	"expense/internal/money"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/streadway/amqp"
@@ -13,12 +19,192 @@ type getExpenseRequest struct {
 	Action   string 				`json:"action"`
 	UserId	 string 				`json:"userId"`
 	Filter   map[string]interface{} `json:"filter"`
+	From	 string					`json:"from,omitempty"`
+	To		 string					`json:"to,omitempty"`
+	Tags	 []string				`json:"tags,omitempty"`
+	TagMode	 string					`json:"tagMode,omitempty"`
+	Type	 string					`json:"type,omitempty"`
+	Query	 string					`json:"query,omitempty"`
+	Limit	 int					`json:"limit,omitempty"`
+	Cursor	 string					`json:"cursor,omitempty"`
+	SortField	 string				`json:"sortField,omitempty"`
+	SortDirection int				`json:"sortDirection,omitempty"`
+	Fields	 []string				`json:"fields,omitempty"`
+	BaseCurrency string				`json:"baseCurrency"`
 }
 
 type getExpenseResponse struct {
 	Message  string 		  `json:"message"`
 	Success  bool 			  `json:"success"`
 	Expenses interface{}	  `json:"expense"`
+	BaseCurrency string		  `json:"baseCurrency"`
+	Total	 string			  `json:"total"`
+	Income	 string			  `json:"income"`
+	Net		 string			  `json:"net"`
+	Totals	 interface{}	  `json:"totals"`
+	MissingRates bool		  `json:"missingRates"`
+	NextCursor	 string		  `json:"nextCursor,omitempty"`
+}
+
+const (
+	maxSearchLength	= 200
+	maxPageSize		= 500
+)
+
+var expenseSortFields = map[string]bool{
+	"date":     true,
+	"amount":   true,
+	"category": true,
+}
+
+var expenseFields = map[string]bool{
+	"expenseId":   true,
+	"description": true,
+	"payee":       true,
+	"notes":       true,
+	"type":        true,
+	"amount":      true,
+	"currency":    true,
+	"category":    true,
+	"accountId":   true,
+	"toAccountId": true,
+	"tags":        true,
+	"date":        true,
+	"createdAt":   true,
+	"updatedAt":   true,
+	"recurringId": true,
+	"externalId":  true,
+	"anomaly":     true,
+	"version":     true,
+}
+
+var expenseQueryParameters = map[string]bool{
+	"expenseId": true,
+	"category":  true,
+	"from":      true,
+	"to":        true,
+	"tag":       true,
+	"tagMode":   true,
+	"type":      true,
+	"currency":  true,
+	"q":         true,
+}
+
+var expensePageParameters = map[string]bool{
+	"limit":  true,
+	"cursor": true,
+	"sort":   true,
+	"fields": true,
+}
+
+// parseExpenseQuery reads the filters of GET /expense, which exports share.
+func parseExpenseQuery(query url.Values, getExpenseRequestData *getExpenseRequest) error {
+	expenseId := query.Get("expenseId")
+	category := query.Get("category")
+	if expenseId != "" && category != "" {
+		return errors.New("At most one of the \"ExpenseId\" or \"Category\" fields can be used!")
+	} else if expenseId != "" && category == "" {
+		getExpenseRequestData.Filter["expenseId"] = expenseId
+	} else if expenseId == "" && category != "" {
+		getExpenseRequestData.Filter["category"] = category
+	}
+
+	if currency := query.Get("currency"); currency != "" {
+		currency = strings.ToUpper(currency)
+		if !money.IsCurrency(currency) {
+			return errors.New("\"Currency\" must be an ISO 4217 currency code!")
+		}
+		getExpenseRequestData.Filter["currency"] = currency
+	}
+
+	from, to, err := ParseDateRange(query.Get("from"), query.Get("to"))
+	if err != nil {
+		return err
+	}
+	getExpenseRequestData.From = from
+	getExpenseRequestData.To = to
+
+	// Tags can be repeated (?tag=a&tag=b) or comma separated (?tag=a,b).
+	tags := make([]string, 0)
+	for _, value := range query["tag"] {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	tags, err = NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	tagMode := query.Get("tagMode")
+	if tagMode != "" && tagMode != "any" && tagMode != "all" {
+		return errors.New("\"TagMode\" must be either \"any\" or \"all\"!")
+	}
+	if tagMode != "" && len(tags) == 0 {
+		return errors.New("\"TagMode\" requires at least one \"Tag\"!")
+	}
+	if len(tags) != 0 {
+		getExpenseRequestData.Tags = tags
+		getExpenseRequestData.TagMode = tagMode
+	}
+
+	if entryType := query.Get("type"); entryType != "" {
+		if !entryTypes[entryType] {
+			return errors.New("\"Type\" must be one of \"expense\", \"income\" or \"transfer\"!")
+		}
+		getExpenseRequestData.Type = entryType
+	}
+
+	q := strings.Join(strings.Fields(query.Get("q")), " ")
+	if len(q) > maxSearchLength {
+		return errors.New("\"Q\" must be at most 200 characters long!")
+	}
+	getExpenseRequestData.Query = q
+	return nil
+}
+
+// parseExpensePage reads the paging, sorting and field selection of GET
+// /expense. Pages are sorted by date, newest first, unless told otherwise.
+func parseExpensePage(query url.Values, getExpenseRequestData *getExpenseRequest) error {
+	if limit := query.Get("limit"); limit != "" {
+		parsedLimit, err := strconv.Atoi(limit)
+		if err != nil || parsedLimit < 1 || parsedLimit > maxPageSize {
+			return errors.New("\"Limit\" must be a number between 1 and 500!")
+		}
+		getExpenseRequestData.Limit = parsedLimit
+	}
+	getExpenseRequestData.Cursor = query.Get("cursor")
+
+	sort := query.Get("sort")
+	if getExpenseRequestData.Query != "" && sort != "" {
+		return errors.New("Search results are ordered by relevance, \"Q\" cannot be combined with \"Sort\"!")
+	}
+	if sort == "" && getExpenseRequestData.Query == "" && (getExpenseRequestData.Limit > 0 || getExpenseRequestData.Cursor != "") {
+		sort = "-date"
+	}
+	if sort != "" {
+		getExpenseRequestData.SortDirection = 1
+		if strings.HasPrefix(sort, "-") {
+			getExpenseRequestData.SortDirection = -1
+			sort = sort[1:]
+		}
+		if !expenseSortFields[sort] {
+			return errors.New("\"Sort\" must be one of \"date\", \"amount\" or \"category\", prefixed with \"-\" for descending order!")
+		}
+		// Minor units of different currencies cannot be compared with each other.
+		if sort == "amount" && getExpenseRequestData.Filter["currency"] == nil {
+			return errors.New("Sorting by \"Amount\" needs a \"Currency\", amounts are only comparable within one currency!")
+		}
+		getExpenseRequestData.SortField = sort
+	}
+
+	if fields := query.Get("fields"); fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			field = strings.TrimSpace(field)
+			if !expenseFields[field] {
+				return errors.New("\"Fields\" contains an unknown field: \"" + field + "\"!")
+			}
+			getExpenseRequestData.Fields = append(getExpenseRequestData.Fields, field)
+		}
+	}
+	return nil
 }
 
 func HandleGetExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
@@ -28,20 +214,20 @@ func HandleGetExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Req
 
 		query := r.URL.Query()
 		for key, _ := range query {
-			if key != "expenseId" && key != "category" {
+			if !expenseQueryParameters[key] && !expensePageParameters[key] {
 				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
 				return
 			}
 		}
-		expenseId := query.Get("expenseId")
-		category := query.Get("category")
-		if expenseId != "" && category != "" {
-			http.Error(w, "At most one of the \"ExpenseId\" or \"Category\" fields can be used!", http.StatusBadRequest)
+		err := parseExpenseQuery(query, getExpenseRequestData)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = parseExpensePage(query, getExpenseRequestData)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
-		} else if expenseId != "" && category == "" {
-			getExpenseRequestData.Filter["expenseId"] = expenseId
-		} else if expenseId == "" && category != "" {
-			getExpenseRequestData.Filter["category"] = category
 		}
 
         correlationId := uuid.New().String()
@@ -65,6 +251,7 @@ func HandleGetExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Req
 		}
 		
 		getExpenseRequestData.UserId = userId
+		getExpenseRequestData.BaseCurrency = middlewares.GetBaseCurrencyFromRequest(r)
         SendRequest(ch, "expenseQueue", "GetExpense", getExpenseRequestData, replyQueue.Name, correlationId)
 
         for message := range messages {
@@ -92,10 +279,24 @@ func HandleGetExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Req
 						expenses = append(expenses, expense)
 					}
 
+					baseCurrency, _ := data["baseCurrency"].(string)
+					total, _ := data["total"].(string)
+					income, _ := data["income"].(string)
+					net, _ := data["net"].(string)
+					missingRates, _ := data["missingRates"].(bool)
+					nextCursor, _ := data["nextCursor"].(string)
+
 					getExpenseResponseData := getExpenseResponse{
 						Message: "Operation is successful!",
 						Success: true,
 						Expenses: expenses,
+						BaseCurrency: baseCurrency,
+						Total: total,
+						Income: income,
+						Net: net,
+						Totals: data["totals"],
+						MissingRates: missingRates,
+						NextCursor: nextCursor,
 					}
 
 					getExpenseResponseDataJSON, err := json.Marshal(getExpenseResponseData)
@@ -108,7 +309,7 @@ func HandleGetExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Req
 					w.WriteHeader(http.StatusOK)
 					w.Write(getExpenseResponseDataJSON)
 				} else {
-					w.WriteHeader(http.StatusUnauthorized)
+					WriteServiceError(w, data)
 				}
 				return
 			}
@@ -121,13 +322,69 @@ type addExpenseRequest struct {
 	Action   	string 	`json:"action"`
 	UserId		string 	`json:"userId"`
 	Description string 	`json:"description"`
-	Amount 		float32 `json:"amount"`
+	Payee		string	`json:"payee"`
+	Notes		string	`json:"notes"`
+	Type		string	`json:"type"`
+	Amount 		json.Number `json:"amount"`
+	Currency	string	`json:"currency"`
 	Category 	string 	`json:"category"`
+	AccountId	string	`json:"accountId"`
+	ToAccountId	string	`json:"toAccountId"`
+	Tags		[]string	`json:"tags"`
+	Date		string	`json:"date,omitempty"`
 }
 
 type addExpenseResponse struct {
 	Message string 		   `json:"message"`
 	Success bool 		   `json:"success"`
+	Anomaly	interface{}	   `json:"anomaly,omitempty"`
+}
+
+// normalizeAddExpense validates a new entry for POST /expense and for batches.
+func normalizeAddExpense(addExpenseRequestData *addExpenseRequest) error {
+	if addExpenseRequestData.Description == "" || addExpenseRequestData.Amount == "" {
+		return errors.New("\"Description\" and \"Amount\" are required!")
+	}
+
+	var err error
+	addExpenseRequestData.Amount, addExpenseRequestData.Currency, err = NormalizeMoney(addExpenseRequestData.Amount, addExpenseRequestData.Currency)
+	if err != nil {
+		return err
+	}
+
+	addExpenseRequestData.Payee, err = NormalizePayee(addExpenseRequestData.Payee)
+	if err != nil {
+		return err
+	}
+
+	addExpenseRequestData.Notes, err = NormalizeNotes(addExpenseRequestData.Notes)
+	if err != nil {
+		return err
+	}
+
+	addExpenseRequestData.Type, err = NormalizeEntryType(addExpenseRequestData.Type, addExpenseRequestData.Amount)
+	if err != nil {
+		return err
+	}
+
+	err = ValidateEntryAccounts(addExpenseRequestData.Type, addExpenseRequestData.AccountId, addExpenseRequestData.ToAccountId)
+	if err != nil {
+		return err
+	}
+
+	addExpenseRequestData.Tags, err = NormalizeTags(addExpenseRequestData.Tags)
+	if err != nil {
+		return err
+	}
+
+	if addExpenseRequestData.Date != "" {
+		date, _, err := ParseDate(addExpenseRequestData.Date)
+		if err != nil {
+			return errors.New("\"Date\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!")
+		}
+		addExpenseRequestData.Date = date.Format(time.RFC3339)
+	}
+	return nil
 }
 
 func HandleAddExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
@@ -139,10 +396,11 @@ func HandleAddExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Req
             return
         }
 
-        if addExpenseRequestData.Description == "" || addExpenseRequestData.Amount == 0 || addExpenseRequestData.Category == "" {
-            http.Error(w, "\"Description\", \"Amount\" and \"Category\" are required!", http.StatusBadRequest)
-            return
-        }
+		err = normalizeAddExpense(addExpenseRequestData)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
         correlationId := uuid.New().String()
 
@@ -189,6 +447,7 @@ func HandleAddExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Req
 					addExpenseResponseData := addExpenseResponse{
 						Message: "Operation is successful!",
 						Success: true,
+						Anomaly: data["anomaly"],
 					}
 
 					addExpenseResponseDataJSON, err := json.Marshal(addExpenseResponseData)
@@ -201,7 +460,7 @@ func HandleAddExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Req
 					w.WriteHeader(http.StatusOK)
 					w.Write(addExpenseResponseDataJSON)
 				} else {
-					w.WriteHeader(http.StatusUnauthorized)
+					WriteServiceError(w, data)
 				}
 				return
 			}
@@ -215,8 +474,17 @@ type updateExpenseRequest struct {
 	UserId		string  `json:"userId"`
 	ExpenseId	string 	`json:"expenseId"`
 	Description string 	`json:"description"`
-	Amount 		float32 `json:"amount"`
+	Payee		string	`json:"payee"`
+	Notes		string	`json:"notes"`
+	Type		string	`json:"type"`
+	Amount 		json.Number `json:"amount"`
+	Currency	string	`json:"currency"`
 	Category 	string 	`json:"category"`
+	AccountId	string	`json:"accountId"`
+	ToAccountId	string	`json:"toAccountId"`
+	Tags		[]string	`json:"tags"`
+	Date		string	`json:"date,omitempty"`
+	Version		*int64	`json:"version,omitempty"`
 }
 
 type updateExpenseResponse struct {
@@ -224,6 +492,53 @@ type updateExpenseResponse struct {
 	Success bool 		   `json:"success"`
 }
 
+// normalizeUpdateExpense validates a complete entry for PUT /expense and for
+// PATCH /expense/{expenseId}, which merges the patch into the stored entry first.
+func normalizeUpdateExpense(updateExpenseRequestData *updateExpenseRequest) error {
+	var err error
+	updateExpenseRequestData.Amount, updateExpenseRequestData.Currency, err = NormalizeMoney(updateExpenseRequestData.Amount, updateExpenseRequestData.Currency)
+	if err != nil {
+		return err
+	}
+
+	updateExpenseRequestData.Payee, err = NormalizePayee(updateExpenseRequestData.Payee)
+	if err != nil {
+		return err
+	}
+
+	updateExpenseRequestData.Notes, err = NormalizeNotes(updateExpenseRequestData.Notes)
+	if err != nil {
+		return err
+	}
+
+	updateExpenseRequestData.Type, err = NormalizeEntryType(updateExpenseRequestData.Type, updateExpenseRequestData.Amount)
+	if err != nil {
+		return err
+	}
+	if updateExpenseRequestData.Type == "expense" && updateExpenseRequestData.Category == "" {
+		return errors.New("\"Category\" is required for expenses!")
+	}
+
+	err = ValidateEntryAccounts(updateExpenseRequestData.Type, updateExpenseRequestData.AccountId, updateExpenseRequestData.ToAccountId)
+	if err != nil {
+		return err
+	}
+
+	updateExpenseRequestData.Tags, err = NormalizeTags(updateExpenseRequestData.Tags)
+	if err != nil {
+		return err
+	}
+
+	if updateExpenseRequestData.Date != "" {
+		date, _, err := ParseDate(updateExpenseRequestData.Date)
+		if err != nil {
+			return errors.New("\"Date\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!")
+		}
+		updateExpenseRequestData.Date = date.Format(time.RFC3339)
+	}
+	return nil
+}
+
 func HandleUpdateExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
     return func(w http.ResponseWriter, r *http.Request) {
         updateExpenseRequestData := &updateExpenseRequest{}
@@ -233,10 +548,27 @@ func HandleUpdateExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.
             return
         }
 
-        if updateExpenseRequestData.ExpenseId == "" || updateExpenseRequestData.Description == "" || updateExpenseRequestData.Amount == 0 || updateExpenseRequestData.Category == "" {
-            http.Error(w, "\"ExpenseId\", \"Description\", \"Amount\" and \"Category\" are required!", http.StatusBadRequest)
+        if updateExpenseRequestData.ExpenseId == "" || updateExpenseRequestData.Description == "" || updateExpenseRequestData.Amount == "" {
+            http.Error(w, "\"ExpenseId\", \"Description\" and \"Amount\" are required!", http.StatusBadRequest)
+            return
+        }
+
+        version, err := ParseIfMatch(r.Header.Get("If-Match"))
+        if err == errIfMatchRequired {
+            http.Error(w, err.Error(), http.StatusPreconditionRequired)
             return
         }
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        updateExpenseRequestData.Version = version
+
+		err = normalizeUpdateExpense(updateExpenseRequestData)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
         correlationId := uuid.New().String()
 
@@ -291,11 +623,14 @@ func HandleUpdateExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.
 						return
 					}
 
+					if version, ok := data["version"].(float64); ok {
+						w.Header().Set("ETag", FormatETag(int64(version)))
+					}
 					w.Header().Set("Content-Type", "application/json")
 					w.WriteHeader(http.StatusOK)
 					w.Write(updateExpenseResponseDataJSON)
 				} else {
-					w.WriteHeader(http.StatusUnauthorized)
+					WriteServiceError(w, data)
 				}
 				return
 			}
@@ -308,6 +643,7 @@ type removeExpenseRequest struct {
 	Action   	string `json:"action"`
 	UserId		string `json:"userId"`
 	ExpenseId	string `json:"expenseId"`
+	Version		*int64 `json:"version,omitempty"`
 }
 
 type removeExpenseResponse struct {
@@ -329,6 +665,17 @@ func HandleRemoveExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.
             return
         }
 
+        version, err := ParseIfMatch(r.Header.Get("If-Match"))
+        if err == errIfMatchRequired {
+            http.Error(w, err.Error(), http.StatusPreconditionRequired)
+            return
+        }
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        removeExpenseRequestData.Version = version
+
         correlationId := uuid.New().String()
 
 		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
@@ -386,7 +733,7 @@ func HandleRemoveExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.
 					w.WriteHeader(http.StatusOK)
 					w.Write(removeExpenseResponseDataJSON)
 				} else {
-					w.WriteHeader(http.StatusUnauthorized)
+					WriteServiceError(w, data)
 				}
 				return
 			}
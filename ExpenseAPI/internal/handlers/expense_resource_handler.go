@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense/internal/middlewares"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/streadway/amqp"
+)
+
+var errExpenseNotFound = errors.New("Expense not found!")
+
+// expensePatchFields are the fields PATCH /expense/{expenseId} can change.
+var expensePatchFields = map[string]bool{
+	"description": true,
+	"payee":       true,
+	"notes":       true,
+	"type":        true,
+	"amount":      true,
+	"currency":    true,
+	"category":    true,
+	"accountId":   true,
+	"toAccountId": true,
+	"tags":        true,
+	"date":        true,
+}
+
+// fetchExpense reads a single entry of the user through the expense service.
+// Its amount is a decimal string in its own currency.
+func fetchExpense(ch *amqp.Channel, r *http.Request, expenseId string) (map[string]interface{}, error) {
+	correlationId := uuid.New().String()
+
+	replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := middlewares.GetUserIdFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	getExpenseRequestData := &getExpenseRequest{
+		UserId:       userId,
+		Filter:       map[string]interface{}{"expenseId": expenseId},
+		BaseCurrency: middlewares.GetBaseCurrencyFromRequest(r),
+	}
+	SendRequest(ch, "expenseQueue", "GetExpense", getExpenseRequestData, replyQueue.Name, correlationId)
+
+	for message := range messages {
+		if message.CorrelationId == correlationId {
+			serviceResponseData := serviceResponse{}
+			err := json.Unmarshal(message.Body, &serviceResponseData)
+			if err != nil {
+				return nil, err
+			}
+
+			data := serviceResponseData.Data
+
+			success, _ := data["success"].(bool)
+			if !success {
+				message, _ := data["message"].(string)
+				return nil, errors.New(message)
+			}
+			expenses, _ := data["expenses"].([]interface{})
+			if len(expenses) == 0 {
+				return nil, errExpenseNotFound
+			}
+			expense, _ := expenses[0].(map[string]interface{})
+			return expense, nil
+		}
+	}
+	return nil, errors.New("No response is received!")
+}
+
+// expenseVersion reads the version of an entry returned by the expense service.
+// Entries stored before versions were kept are at version 0.
+func expenseVersion(expense map[string]interface{}) int64 {
+	version, _ := expense["version"].(float64)
+	return int64(version)
+}
+
+type getExpenseByIdResponse struct {
+	Message	string					`json:"message"`
+	Success	bool					`json:"success"`
+	Expense	map[string]interface{}	`json:"expense"`
+}
+
+func HandleGetExpenseByIdRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expense, err := fetchExpense(ch, r, mux.Vars(r)["expenseId"])
+		if err == errExpenseNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to get expense!", http.StatusInternalServerError)
+			return
+		}
+
+		getExpenseByIdResponseData := getExpenseByIdResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Expense: expense,
+		}
+
+		getExpenseByIdResponseDataJSON, err := json.Marshal(getExpenseByIdResponseData)
+		if err != nil {
+			http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", FormatETag(expenseVersion(expense)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(getExpenseByIdResponseDataJSON)
+	}
+}
+
+func HandlePatchExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		patch := map[string]json.RawMessage{}
+		err := json.NewDecoder(r.Body).Decode(&patch)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+		for key, _ := range patch {
+			if !expensePatchFields[key] {
+				http.Error(w, "\"" + key + "\" cannot be changed!", http.StatusBadRequest)
+				return
+			}
+		}
+		version, err := ParseIfMatch(r.Header.Get("If-Match"))
+		if err == errIfMatchRequired {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		expenseId := mux.Vars(r)["expenseId"]
+		expense, err := fetchExpense(ch, r, expenseId)
+		if err == errExpenseNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to get expense!", http.StatusInternalServerError)
+			return
+		}
+		// The patch is merged into this read, so the update must not apply to any later version.
+		currentVersion := expenseVersion(expense)
+		if version != nil && *version != currentVersion {
+			http.Error(w, "Expense was changed in the meantime!", http.StatusPreconditionFailed)
+			return
+		}
+
+		// JSON Merge Patch (RFC 7396): supplied fields replace the stored ones and
+		// null removes a field; everything else is kept.
+		merged := make(map[string]interface{})
+		for key, _ := range expensePatchFields {
+			if value, exists := expense[key]; exists {
+				merged[key] = value
+			}
+		}
+		for key, value := range patch {
+			if string(value) == "null" {
+				delete(merged, key)
+				continue
+			}
+			merged[key] = value
+		}
+		mergedJSON, err := json.Marshal(merged)
+		if err != nil {
+			http.Error(w, "Failed to merge expense!", http.StatusInternalServerError)
+			return
+		}
+		updateExpenseRequestData := &updateExpenseRequest{}
+		err = json.Unmarshal(mergedJSON, &updateExpenseRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+		updateExpenseRequestData.ExpenseId = expenseId
+		updateExpenseRequestData.Version = &currentVersion
+
+		if updateExpenseRequestData.Description == "" || updateExpenseRequestData.Amount == "" {
+			http.Error(w, "\"Description\" and \"Amount\" are required!", http.StatusBadRequest)
+			return
+		}
+		err = normalizeUpdateExpense(updateExpenseRequestData)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		updateExpenseRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "UpdateExpense", updateExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					updateExpenseResponseData := updateExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					updateExpenseResponseDataJSON, err := json.Marshal(updateExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					if version, ok := data["version"].(float64); ok {
+						w.Header().Set("ETag", FormatETag(int64(version)))
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(updateExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+func HandleRemoveExpenseByIdRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version, err := ParseIfMatch(r.Header.Get("If-Match"))
+		if err == errIfMatchRequired {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		removeExpenseRequestData := &removeExpenseRequest{
+			ExpenseId: mux.Vars(r)["expenseId"],
+			Version:   version,
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		removeExpenseRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "RemoveExpense", removeExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					removeExpenseResponseData := removeExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					removeExpenseResponseDataJSON, err := json.Marshal(removeExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(removeExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense/internal/exporter"
+	"expense/internal/middlewares"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+var exportMediaTypes = map[string]string{
+	"text/csv":             "csv",
+	"application/x-ndjson": "ndjson",
+	"application/jsonl":    "ndjson",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": "xlsx",
+}
+
+// exportFormat picks the format of an export from the "format" parameter or,
+// without it, from the first supported media type of the Accept header.
+// Exports are CSV by default.
+func exportFormat(format string, accept string) (string, error) {
+	if format != "" {
+		format = strings.ToLower(format)
+		if _, ok := exporter.ContentTypes[format]; !ok {
+			return "", errors.New("\"Format\" must be one of \"csv\", \"ndjson\" or \"xlsx\"!")
+		}
+		return format, nil
+	}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		if format, ok := exportMediaTypes[mediaType]; ok {
+			return format, nil
+		}
+	}
+	return "csv", nil
+}
+
+type exportExpensesChunk struct {
+	Action	string	`json:"action"`
+	Data	struct {
+		Message		string				`json:"message"`
+		Success		bool				`json:"success"`
+		Done		bool				`json:"done"`
+		Status		int					`json:"status"`
+		NextTo		string				`json:"nextTo"`
+		Expenses	[]exporter.Record	`json:"expenses"`
+	}	`json:"data"`
+}
+
+func HandleExportExpensesRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exportExpensesRequestData := &getExpenseRequest{}
+		exportExpensesRequestData.Filter = make(map[string]interface{})
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if !expenseQueryParameters[key] && key != "format" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		format, err := exportFormat(query.Get("format"), r.Header.Get("Accept"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = parseExpenseQuery(query, exportExpensesRequestData)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		exportExpensesRequestData.UserId = userId
+		exportExpensesRequestData.BaseCurrency = middlewares.GetBaseCurrencyFromRequest(r)
+		SendRequest(ch, "expenseQueue", "ExportExpenses", exportExpensesRequestData, replyQueue.Name, correlationId)
+
+		// The export arrives in chunks that are written as they come in. Once the
+		// first chunk is written the status is sent, so later failures can only
+		// cut the response short.
+		var writer exporter.Writer
+		for message := range messages {
+			if message.CorrelationId != correlationId {
+				continue
+			}
+
+			chunk := exportExpensesChunk{}
+			err := json.Unmarshal(message.Body, &chunk)
+			if err != nil {
+				if writer == nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+				}
+				return
+			}
+			if !chunk.Data.Success {
+				if writer == nil && chunk.Data.Status != 0 {
+					http.Error(w, chunk.Data.Message, chunk.Data.Status)
+				} else if writer == nil {
+					http.Error(w, "An error occured!", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			if writer == nil {
+				w.Header().Set("Content-Type", exporter.ContentTypes[format])
+				w.Header().Set("Content-Disposition", "attachment; filename=\"expenses."+format+"\"")
+				w.WriteHeader(http.StatusOK)
+				writer, err = exporter.NewWriter(format, w)
+				if err != nil {
+					return
+				}
+			}
+			for _, record := range chunk.Data.Expenses {
+				if err := writer.Write(record); err != nil {
+					return
+				}
+			}
+			if chunk.Data.Done {
+				writer.Close()
+				return
+			}
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			// The next chunk is asked for once this one is written, so the
+			// export goes no faster than the client reads it.
+			SendRequest(ch, chunk.Data.NextTo, "ExportNext", nil, replyQueue.Name, correlationId)
+		}
+		if writer == nil {
+			http.Error(w, "No response is received!", http.StatusRequestTimeout)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense/internal/middlewares"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+const maxForecastHistory = 24
+
+type getForecastRequest struct {
+	Action			string	`json:"action"`
+	UserId			string	`json:"userId"`
+	Date			string	`json:"date,omitempty"`
+	Period			string	`json:"period"`
+	History			int		`json:"history"`
+	BaseCurrency	string	`json:"baseCurrency"`
+}
+
+type getForecastResponse struct {
+	Message			string		`json:"message"`
+	Success			bool		`json:"success"`
+	BaseCurrency	string		`json:"baseCurrency"`
+	PeriodStart		string		`json:"periodStart"`
+	PeriodEnd		string		`json:"periodEnd"`
+	Elapsed			float64		`json:"elapsed"`
+	HistoryPeriods	float64		`json:"historyPeriods"`
+	Categories		interface{}	`json:"categories"`
+	Spent			string		`json:"spent"`
+	Forecast		string		`json:"forecast"`
+	Low				string		`json:"low"`
+	High			string		`json:"high"`
+	MissingRates	bool		`json:"missingRates"`
+}
+
+func HandleGetForecastRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getForecastRequestData := &getForecastRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "date" && key != "period" && key != "history" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// A date-only value includes the spending of that whole day.
+		if date := query.Get("date"); date != "" {
+			parsedDate, dateOnly, err := ParseDate(date)
+			if err != nil {
+				http.Error(w, "\"Date\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!", http.StatusBadRequest)
+				return
+			}
+			if dateOnly {
+				parsedDate = parsedDate.AddDate(0, 0, 1)
+			}
+			getForecastRequestData.Date = parsedDate.Format(time.RFC3339)
+		}
+
+		getForecastRequestData.Period = query.Get("period")
+		if getForecastRequestData.Period == "" {
+			getForecastRequestData.Period = "monthly"
+		}
+		if !budgetPeriods[getForecastRequestData.Period] {
+			http.Error(w, "\"Period\" must be either \"weekly\" or \"monthly\"!", http.StatusBadRequest)
+			return
+		}
+
+		if history := query.Get("history"); history != "" {
+			parsedHistory, err := strconv.Atoi(history)
+			if err != nil || parsedHistory < 1 || parsedHistory > maxForecastHistory {
+				http.Error(w, "\"History\" must be a number of periods between 1 and 24!", http.StatusBadRequest)
+				return
+			}
+			getForecastRequestData.History = parsedHistory
+		}
+		getForecastRequestData.BaseCurrency = middlewares.GetBaseCurrencyFromRequest(r)
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getForecastRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetForecast", getForecastRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					baseCurrency, _ := data["baseCurrency"].(string)
+					periodStart, _ := data["periodStart"].(string)
+					periodEnd, _ := data["periodEnd"].(string)
+					elapsed, _ := data["elapsed"].(float64)
+					historyPeriods, _ := data["historyPeriods"].(float64)
+					spent, _ := data["spent"].(string)
+					forecast, _ := data["forecast"].(string)
+					low, _ := data["low"].(string)
+					high, _ := data["high"].(string)
+					missingRates, _ := data["missingRates"].(bool)
+
+					getForecastResponseData := getForecastResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						BaseCurrency: baseCurrency,
+						PeriodStart: periodStart,
+						PeriodEnd: periodEnd,
+						Elapsed: elapsed,
+						HistoryPeriods: historyPeriods,
+						Categories: data["categories"],
+						Spent: spent,
+						Forecast: forecast,
+						Low: low,
+						High: high,
+						MissingRates: missingRates,
+					}
+
+					getForecastResponseDataJSON, err := json.Marshal(getForecastResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getForecastResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
@@ -0,0 +1,1026 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense/internal/middlewares"
+	"expense/internal/money"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+var groupSplitTypes = map[string]bool{
+	"equal":  true,
+	"shares": true,
+	"exact":  true,
+}
+
+type getGroupRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type getGroupResponse struct {
+	Message	string		`json:"message"`
+	Success	bool		`json:"success"`
+	Groups	interface{}	`json:"groups"`
+}
+
+func HandleGetGroupRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getGroupRequestData := &getGroupRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "groupId" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getGroupRequestData.GroupId = query.Get("groupId")
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getGroupRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "GetGroup", getGroupRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getGroupResponseData := getGroupResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Groups: data["groups"],
+					}
+
+					getGroupResponseDataJSON, err := json.Marshal(getGroupResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getGroupResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type addGroupRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	Name		string		`json:"name"`
+	Currency	string		`json:"currency"`
+	MemberIds	[]string	`json:"memberIds"`
+}
+
+type addGroupResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	GroupId	string	`json:"groupId"`
+}
+
+func HandleAddGroupRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addGroupRequestData := &addGroupRequest{}
+		err := json.NewDecoder(r.Body).Decode(&addGroupRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if addGroupRequestData.Name == "" {
+			http.Error(w, "\"Name\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		// Groups use the base currency of their creator unless another one is given.
+		if addGroupRequestData.Currency == "" {
+			addGroupRequestData.Currency = middlewares.GetBaseCurrencyFromRequest(r)
+		}
+		addGroupRequestData.Currency = strings.ToUpper(addGroupRequestData.Currency)
+		if !money.IsCurrency(addGroupRequestData.Currency) {
+			http.Error(w, "\"Currency\" must be an ISO 4217 currency code!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		addGroupRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "AddGroup", addGroupRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					groupId, _ := data["groupId"].(string)
+
+					addGroupResponseData := addGroupResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						GroupId: groupId,
+					}
+
+					addGroupResponseDataJSON, err := json.Marshal(addGroupResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(addGroupResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type updateGroupRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	GroupId		string		`json:"groupId"`
+	Name		string		`json:"name"`
+	MemberIds	[]string	`json:"memberIds"`
+}
+
+type updateGroupResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleUpdateGroupRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		updateGroupRequestData := &updateGroupRequest{}
+		err := json.NewDecoder(r.Body).Decode(&updateGroupRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if updateGroupRequestData.GroupId == "" || updateGroupRequestData.Name == "" {
+			http.Error(w, "\"GroupId\" and \"Name\" are required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		updateGroupRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "UpdateGroup", updateGroupRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					updateGroupResponseData := updateGroupResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					updateGroupResponseDataJSON, err := json.Marshal(updateGroupResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(updateGroupResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type removeGroupRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type removeGroupResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRemoveGroupRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		removeGroupRequestData := &removeGroupRequest{}
+		err := json.NewDecoder(r.Body).Decode(&removeGroupRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if removeGroupRequestData.GroupId == "" {
+			http.Error(w, "\"GroupId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		removeGroupRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "RemoveGroup", removeGroupRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					removeGroupResponseData := removeGroupResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					removeGroupResponseDataJSON, err := json.Marshal(removeGroupResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(removeGroupResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type getGroupExpenseRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type getGroupExpenseResponse struct {
+	Message			string		`json:"message"`
+	Success			bool		`json:"success"`
+	GroupExpenses	interface{}	`json:"groupExpenses"`
+}
+
+func HandleGetGroupExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getGroupExpenseRequestData := &getGroupExpenseRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "groupId" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getGroupExpenseRequestData.GroupId = query.Get("groupId")
+		if getGroupExpenseRequestData.GroupId == "" {
+			http.Error(w, "\"GroupId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getGroupExpenseRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "GetGroupExpense", getGroupExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getGroupExpenseResponseData := getGroupExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						GroupExpenses: data["groupExpenses"],
+					}
+
+					getGroupExpenseResponseDataJSON, err := json.Marshal(getGroupExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getGroupExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type groupSplitRequest struct {
+	UserId	string		`json:"userId"`
+	Shares	int64		`json:"shares,omitempty"`
+	Amount	json.Number	`json:"amount,omitempty"`
+}
+
+type addGroupExpenseRequest struct {
+	Action		string				`json:"action"`
+	UserId		string				`json:"userId"`
+	GroupId		string				`json:"groupId"`
+	Description	string				`json:"description"`
+	Amount		json.Number			`json:"amount"`
+	Currency	string				`json:"currency"`
+	PaidBy		string				`json:"paidBy"`
+	SplitType	string				`json:"splitType"`
+	Splits		[]groupSplitRequest	`json:"splits"`
+	Date		string				`json:"date,omitempty"`
+}
+
+type addGroupExpenseResponse struct {
+	Message			string	`json:"message"`
+	Success			bool	`json:"success"`
+	GroupExpenseId	string	`json:"groupExpenseId"`
+}
+
+func HandleAddGroupExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addGroupExpenseRequestData := &addGroupExpenseRequest{}
+		err := json.NewDecoder(r.Body).Decode(&addGroupExpenseRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if addGroupExpenseRequestData.GroupId == "" || addGroupExpenseRequestData.Description == "" || addGroupExpenseRequestData.Amount == "" {
+			http.Error(w, "\"GroupId\", \"Description\" and \"Amount\" are required!", http.StatusBadRequest)
+			return
+		}
+
+		if addGroupExpenseRequestData.SplitType == "" {
+			addGroupExpenseRequestData.SplitType = "equal"
+		}
+		if !groupSplitTypes[addGroupExpenseRequestData.SplitType] {
+			http.Error(w, "\"SplitType\" must be \"equal\", \"shares\" or \"exact\"!", http.StatusBadRequest)
+			return
+		}
+		addGroupExpenseRequestData.Currency = strings.ToUpper(addGroupExpenseRequestData.Currency)
+
+		for _, split := range addGroupExpenseRequestData.Splits {
+			if split.UserId == "" {
+				http.Error(w, "\"UserId\" is required for each split!", http.StatusBadRequest)
+				return
+			}
+			if addGroupExpenseRequestData.SplitType == "shares" && (split.Shares <= 0 || split.Shares > 1000000) {
+				http.Error(w, "\"Shares\" must be a positive number for each split!", http.StatusBadRequest)
+				return
+			}
+			if addGroupExpenseRequestData.SplitType == "exact" && split.Amount == "" {
+				http.Error(w, "\"Amount\" is required for each split!", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if addGroupExpenseRequestData.Date != "" {
+			date, _, err := ParseDate(addGroupExpenseRequestData.Date)
+			if err != nil {
+				http.Error(w, "\"Date\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!", http.StatusBadRequest)
+				return
+			}
+			addGroupExpenseRequestData.Date = date.Format(time.RFC3339)
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		addGroupExpenseRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "AddGroupExpense", addGroupExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					groupExpenseId, _ := data["groupExpenseId"].(string)
+
+					addGroupExpenseResponseData := addGroupExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						GroupExpenseId: groupExpenseId,
+					}
+
+					addGroupExpenseResponseDataJSON, err := json.Marshal(addGroupExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(addGroupExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type removeGroupExpenseRequest struct {
+	Action			string	`json:"action"`
+	UserId			string	`json:"userId"`
+	GroupExpenseId	string	`json:"groupExpenseId"`
+}
+
+type removeGroupExpenseResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRemoveGroupExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		removeGroupExpenseRequestData := &removeGroupExpenseRequest{}
+		err := json.NewDecoder(r.Body).Decode(&removeGroupExpenseRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if removeGroupExpenseRequestData.GroupExpenseId == "" {
+			http.Error(w, "\"GroupExpenseId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		removeGroupExpenseRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "RemoveGroupExpense", removeGroupExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					removeGroupExpenseResponseData := removeGroupExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					removeGroupExpenseResponseDataJSON, err := json.Marshal(removeGroupExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(removeGroupExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type getGroupBalanceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type getGroupBalanceResponse struct {
+	Message		string		`json:"message"`
+	Success		bool		`json:"success"`
+	Currency	string		`json:"currency"`
+	Balances	interface{}	`json:"balances"`
+	Debts		interface{}	`json:"debts"`
+}
+
+func HandleGetGroupBalanceRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getGroupBalanceRequestData := &getGroupBalanceRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "groupId" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getGroupBalanceRequestData.GroupId = query.Get("groupId")
+		if getGroupBalanceRequestData.GroupId == "" {
+			http.Error(w, "\"GroupId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getGroupBalanceRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "GetGroupBalance", getGroupBalanceRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					currency, _ := data["currency"].(string)
+
+					getGroupBalanceResponseData := getGroupBalanceResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Currency: currency,
+						Balances: data["balances"],
+						Debts: data["debts"],
+					}
+
+					getGroupBalanceResponseDataJSON, err := json.Marshal(getGroupBalanceResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getGroupBalanceResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type getSettlementRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type getSettlementResponse struct {
+	Message		string		`json:"message"`
+	Success		bool		`json:"success"`
+	Settlements	interface{}	`json:"settlements"`
+}
+
+func HandleGetSettlementRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getSettlementRequestData := &getSettlementRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "groupId" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getSettlementRequestData.GroupId = query.Get("groupId")
+		if getSettlementRequestData.GroupId == "" {
+			http.Error(w, "\"GroupId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getSettlementRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "GetSettlement", getSettlementRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getSettlementResponseData := getSettlementResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Settlements: data["settlements"],
+					}
+
+					getSettlementResponseDataJSON, err := json.Marshal(getSettlementResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getSettlementResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type addSettlementRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	GroupId		string		`json:"groupId"`
+	FromUserId	string		`json:"fromUserId"`
+	ToUserId	string		`json:"toUserId"`
+	Amount		json.Number	`json:"amount"`
+	Date		string		`json:"date,omitempty"`
+}
+
+type addSettlementResponse struct {
+	Message			string	`json:"message"`
+	Success			bool	`json:"success"`
+	SettlementId	string	`json:"settlementId"`
+}
+
+func HandleAddSettlementRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addSettlementRequestData := &addSettlementRequest{}
+		err := json.NewDecoder(r.Body).Decode(&addSettlementRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if addSettlementRequestData.GroupId == "" || addSettlementRequestData.ToUserId == "" || addSettlementRequestData.Amount == "" {
+			http.Error(w, "\"GroupId\", \"ToUserId\" and \"Amount\" are required!", http.StatusBadRequest)
+			return
+		}
+
+		if addSettlementRequestData.Date != "" {
+			date, _, err := ParseDate(addSettlementRequestData.Date)
+			if err != nil {
+				http.Error(w, "\"Date\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!", http.StatusBadRequest)
+				return
+			}
+			addSettlementRequestData.Date = date.Format(time.RFC3339)
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		addSettlementRequestData.UserId = userId
+		SendRequest(ch, "groupQueue", "AddSettlement", addSettlementRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					settlementId, _ := data["settlementId"].(string)
+
+					addSettlementResponseData := addSettlementResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						SettlementId: settlementId,
+					}
+
+					addSettlementResponseDataJSON, err := json.Marshal(addSettlementResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(addSettlementResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
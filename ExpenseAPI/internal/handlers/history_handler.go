@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense/internal/middlewares"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/streadway/amqp"
+)
+
+type getExpenseHistoryRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+}
+
+type getExpenseHistoryResponse struct {
+	Message	string		`json:"message"`
+	Success	bool		`json:"success"`
+	History	interface{}	`json:"history"`
+}
+
+func HandleGetExpenseHistoryRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getExpenseHistoryRequestData := &getExpenseHistoryRequest{}
+
+		if len(r.URL.Query()) > 0 {
+			http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+			return
+		}
+		getExpenseHistoryRequestData.ExpenseId = mux.Vars(r)["expenseId"]
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getExpenseHistoryRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetExpenseHistory", getExpenseHistoryRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getExpenseHistoryResponseData := getExpenseHistoryResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						History: data["history"],
+					}
+
+					getExpenseHistoryResponseDataJSON, err := json.Marshal(getExpenseHistoryResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getExpenseHistoryResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type revertExpenseRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+	HistoryId	string	`json:"historyId"`
+}
+
+type revertExpenseResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRevertExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		revertExpenseRequestData := &revertExpenseRequest{}
+		err := json.NewDecoder(r.Body).Decode(&revertExpenseRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if revertExpenseRequestData.HistoryId == "" {
+			http.Error(w, "\"HistoryId\" is required!", http.StatusBadRequest)
+			return
+		}
+		revertExpenseRequestData.ExpenseId = mux.Vars(r)["expenseId"]
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		revertExpenseRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "RevertExpense", revertExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					revertExpenseResponseData := revertExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					revertExpenseResponseDataJSON, err := json.Marshal(revertExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(revertExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense/internal/importer"
+	"expense/internal/middlewares"
+	"expense/internal/money"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+const maxImportSize = 5 << 20
+
+type importExpensesRequest struct {
+	Action	string				`json:"action"`
+	UserId	string				`json:"userId"`
+	DryRun	bool				`json:"dryRun"`
+	Rows	[]importer.Row		`json:"rows"`
+	Errors	[]importer.RowError	`json:"errors"`
+}
+
+type importExpensesResponse struct {
+	Message		string		`json:"message"`
+	Success		bool		`json:"success"`
+	DryRun		bool		`json:"dryRun"`
+	Valid		interface{}	`json:"valid"`
+	Imported	interface{}	`json:"imported"`
+	Duplicates	interface{}	`json:"duplicates"`
+	Errors		interface{}	`json:"errors"`
+}
+
+// parseImportForm reads the options shared by all formats from the multipart form.
+// Imports are dry runs unless "dryRun" is set to false.
+func parseImportForm(r *http.Request) (bool, string, string, error) {
+	dryRun := true
+	if value := r.FormValue("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, "", "", errors.New("\"DryRun\" must be either \"true\" or \"false\"!")
+		}
+		dryRun = parsed
+	}
+
+	dateFormat := strings.ToUpper(r.FormValue("dateFormat"))
+	if _, ok := importer.DateFormats[dateFormat]; dateFormat != "" && !ok {
+		return false, "", "", errors.New("\"DateFormat\" must be one of \"YYYY-MM-DD\", \"DD.MM.YYYY\", \"DD/MM/YYYY\" or \"MM/DD/YYYY\"!")
+	}
+
+	currency := strings.ToUpper(r.FormValue("currency"))
+	if currency == "" {
+		currency = money.DefaultCurrency()
+	}
+	if !money.IsCurrency(currency) {
+		return false, "", "", errors.New("\"Currency\" must be an ISO 4217 currency code!")
+	}
+	return dryRun, dateFormat, currency, nil
+}
+
+// importFormat returns the format of an imported file. Without an explicit
+// format, it is taken from the file extension and defaults to CSV.
+func importFormat(format string, fileName string) (string, error) {
+	format = strings.ToLower(format)
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(fileName)) {
+		case ".ofx", ".qfx":
+			format = "ofx"
+		case ".qif":
+			format = "qif"
+		default:
+			format = "csv"
+		}
+	}
+	if format != "csv" && format != "ofx" && format != "qif" {
+		return "", errors.New("\"Format\" must be one of \"csv\", \"ofx\" or \"qif\"!")
+	}
+	return format, nil
+}
+
+// readImportFile reads the uploaded file of an import.
+func readImportFile(w http.ResponseWriter, r *http.Request) (multipart.File, string, bool) {
+	// The limit leaves some room for the multipart framing and the form fields.
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+(1<<20))
+	err := r.ParseMultipartForm(maxImportSize)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, "File must be at most 5 MB!", http.StatusRequestEntityTooLarge)
+			return nil, "", false
+		}
+		http.Error(w, "Format is invalid!", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "\"File\" is required!", http.StatusBadRequest)
+		return nil, "", false
+	}
+	if fileHeader.Size > maxImportSize {
+		file.Close()
+		http.Error(w, "File must be at most 5 MB!", http.StatusRequestEntityTooLarge)
+		return nil, "", false
+	}
+	return file, fileHeader.Filename, true
+}
+
+func HandleImportExpensesRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		importExpensesRequestData := &importExpensesRequest{}
+
+		file, fileName, ok := readImportFile(w, r)
+		if !ok {
+			return
+		}
+		defer file.Close()
+
+		format, err := importFormat(r.FormValue("format"), fileName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		dryRun, dateFormat, currency, err := parseImportForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		importExpensesRequestData.DryRun = dryRun
+
+		decimalSeparator := r.FormValue("decimalSeparator")
+		if decimalSeparator != "" && decimalSeparator != "." && decimalSeparator != "," {
+			http.Error(w, "\"DecimalSeparator\" must be either \".\" or \",\"!", http.StatusBadRequest)
+			return
+		}
+
+		switch format {
+		case "ofx":
+			importExpensesRequestData.Rows, importExpensesRequestData.Errors, err = importer.ParseOFX(file, currency)
+		case "qif":
+			importExpensesRequestData.Rows, importExpensesRequestData.Errors, err = importer.ParseQIF(file, currency, dateFormat, decimalSeparator == ",")
+		default:
+			mapping := importer.Mapping{}
+			err = json.Unmarshal([]byte(r.FormValue("mapping")), &mapping)
+			if err != nil {
+				http.Error(w, "\"Mapping\" must be a JSON object!", http.StatusBadRequest)
+				return
+			}
+			if mapping.Date == "" || mapping.Amount == "" || mapping.Description == "" {
+				http.Error(w, "\"Mapping\" must name the \"date\", \"amount\" and \"description\" columns!", http.StatusBadRequest)
+				return
+			}
+
+			options := importer.CSVOptions{
+				Mapping:        mapping,
+				DateFormat:     dateFormat,
+				Currency:       currency,
+				DecimalComma:   decimalSeparator == ",",
+				SignConvention: r.FormValue("amountSign"),
+			}
+
+			delimiter := r.FormValue("delimiter")
+			if delimiter == "tab" {
+				delimiter = "\t"
+			}
+			if delimiter != "" {
+				options.Delimiter, _ = utf8.DecodeRuneInString(delimiter)
+				if utf8.RuneCountInString(delimiter) != 1 || options.Delimiter == '"' || options.Delimiter == '\n' || options.Delimiter == '\r' {
+					http.Error(w, "\"Delimiter\" must be a single character!", http.StatusBadRequest)
+					return
+				}
+			}
+
+			if options.SignConvention == "" {
+				options.SignConvention = importer.PositiveExpense
+			}
+			if !importer.SignConventions[options.SignConvention] {
+				http.Error(w, "\"AmountSign\" must be either \"positive-expense\" or \"negative-expense\"!", http.StatusBadRequest)
+				return
+			}
+
+			importExpensesRequestData.Rows, importExpensesRequestData.Errors, err = importer.ParseCSV(file, options)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		importExpensesRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "ImportExpenses", importExpensesRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					importExpensesResponseData := importExpensesResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						DryRun: importExpensesRequestData.DryRun,
+						Valid: data["valid"],
+						Imported: data["imported"],
+						Duplicates: data["duplicates"],
+						Errors: data["errors"],
+					}
+
+					importExpensesResponseDataJSON, err := json.Marshal(importExpensesResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(importExpensesResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
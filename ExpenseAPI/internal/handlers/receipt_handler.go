@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"expense/internal/middlewares"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+const maxReceiptSize = 5 << 20
+
+// The content type is sniffed from the file itself rather than trusted from the client.
+var receiptContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+type getReceiptRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ReceiptId	string	`json:"receiptId"`
+	ExpenseId	string	`json:"expenseId"`
+}
+
+type getReceiptResponse struct {
+	Message		string		`json:"message"`
+	Success		bool		`json:"success"`
+	Receipts	interface{}	`json:"receipts"`
+}
+
+func HandleGetReceiptRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getReceiptRequestData := &getReceiptRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "receiptId" && key != "expenseId" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getReceiptRequestData.ReceiptId = query.Get("receiptId")
+		getReceiptRequestData.ExpenseId = query.Get("expenseId")
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getReceiptRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetReceipt", getReceiptRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					// A single receipt is downloaded, otherwise the matching receipts are listed.
+					if getReceiptRequestData.ReceiptId != "" {
+						receipts, _ := data["receipts"].([]interface{})
+						encodedContent, _ := data["content"].(string)
+						content, err := base64.StdEncoding.DecodeString(encodedContent)
+						if err != nil || len(receipts) == 0 {
+							http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+							return
+						}
+						receipt, _ := receipts[0].(map[string]interface{})
+						contentType, _ := receipt["contentType"].(string)
+						fileName, _ := receipt["fileName"].(string)
+
+						w.Header().Set("Content-Type", contentType)
+						w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+						w.WriteHeader(http.StatusOK)
+						w.Write(content)
+						return
+					}
+
+					getReceiptResponseData := getReceiptResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Receipts: data["receipts"],
+					}
+
+					getReceiptResponseDataJSON, err := json.Marshal(getReceiptResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getReceiptResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type addReceiptRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+	FileName	string	`json:"fileName"`
+	ContentType	string	`json:"contentType"`
+	Content		[]byte	`json:"content"`
+}
+
+type addReceiptResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	ReceiptId	string	`json:"receiptId"`
+}
+
+func HandleAddReceiptRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addReceiptRequestData := &addReceiptRequest{}
+
+		// The limit leaves some room for the multipart framing and the form fields.
+		r.Body = http.MaxBytesReader(w, r.Body, maxReceiptSize+(1<<20))
+		err := r.ParseMultipartForm(maxReceiptSize)
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				http.Error(w, "Receipt must be at most 5 MB!", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		addReceiptRequestData.ExpenseId = r.FormValue("expenseId")
+		if addReceiptRequestData.ExpenseId == "" {
+			http.Error(w, "\"ExpenseId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		file, fileHeader, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "\"File\" is required!", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		if fileHeader.Size > maxReceiptSize {
+			http.Error(w, "Receipt must be at most 5 MB!", http.StatusRequestEntityTooLarge)
+			return
+		}
+		content, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+		if len(content) == 0 {
+			http.Error(w, "Receipt must not be empty!", http.StatusBadRequest)
+			return
+		}
+
+		contentType := http.DetectContentType(content)
+		if !receiptContentTypes[contentType] {
+			http.Error(w, "Receipt must be a JPEG, PNG, WebP or PDF file!", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		addReceiptRequestData.FileName = filepath.Base(fileHeader.Filename)
+		addReceiptRequestData.ContentType = contentType
+		addReceiptRequestData.Content = content
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		addReceiptRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "AddReceipt", addReceiptRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					receiptId, _ := data["receiptId"].(string)
+
+					addReceiptResponseData := addReceiptResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						ReceiptId: receiptId,
+					}
+
+					addReceiptResponseDataJSON, err := json.Marshal(addReceiptResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(addReceiptResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type removeReceiptRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ReceiptId	string	`json:"receiptId"`
+}
+
+type removeReceiptResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRemoveReceiptRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		removeReceiptRequestData := &removeReceiptRequest{}
+		err := json.NewDecoder(r.Body).Decode(&removeReceiptRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if removeReceiptRequestData.ReceiptId == "" {
+			http.Error(w, "\"ReceiptId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		removeReceiptRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "RemoveReceipt", removeReceiptRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					removeReceiptResponseData := removeReceiptResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					removeReceiptResponseDataJSON, err := json.Marshal(removeReceiptResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(removeReceiptResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense/internal/middlewares"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+var recurringFrequencies = map[string]bool{
+	"daily":   true,
+	"weekly":  true,
+	"monthly": true,
+	"yearly":  true,
+}
+
+func normalizeRecurringSchedule(frequency string, interval int, startDate string, endDate string) (string, int, string, string, error) {
+	frequency = strings.ToLower(frequency)
+	if !recurringFrequencies[frequency] {
+		return "", 0, "", "", errors.New("\"Frequency\" must be one of \"daily\", \"weekly\", \"monthly\" or \"yearly\"!")
+	}
+	if interval == 0 {
+		interval = 1
+	}
+	if interval < 0 {
+		return "", 0, "", "", errors.New("\"Interval\" must be positive!")
+	}
+
+	start := time.Now().UTC()
+	if startDate != "" {
+		date, _, err := ParseDate(startDate)
+		if err != nil {
+			return "", 0, "", "", errors.New("\"StartDate\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!")
+		}
+		start = date
+	}
+	startDate = start.Format(time.RFC3339)
+
+	if endDate != "" {
+		end, dateOnly, err := ParseDate(endDate)
+		if err != nil {
+			return "", 0, "", "", errors.New("\"EndDate\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!")
+		}
+		// A plain end date still allows an occurrence later on that day.
+		if dateOnly {
+			end = end.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		if end.Before(start) {
+			return "", 0, "", "", errors.New("\"EndDate\" must not be earlier than \"StartDate\"!")
+		}
+		endDate = end.Format(time.RFC3339)
+	}
+	return frequency, interval, startDate, endDate, nil
+}
+
+type getRecurringExpenseRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	RecurringId	string	`json:"recurringId"`
+}
+
+type getRecurringExpenseResponse struct {
+	Message				string		`json:"message"`
+	Success				bool		`json:"success"`
+	RecurringExpenses	interface{}	`json:"recurringExpenses"`
+}
+
+func HandleGetRecurringExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getRecurringExpenseRequestData := &getRecurringExpenseRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "recurringId" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getRecurringExpenseRequestData.RecurringId = query.Get("recurringId")
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getRecurringExpenseRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetRecurringExpense", getRecurringExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getRecurringExpenseResponseData := getRecurringExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						RecurringExpenses: data["recurringExpenses"],
+					}
+
+					getRecurringExpenseResponseDataJSON, err := json.Marshal(getRecurringExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getRecurringExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type addRecurringExpenseRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	Description	string		`json:"description"`
+	Type		string		`json:"type"`
+	Amount		json.Number	`json:"amount"`
+	Currency	string		`json:"currency"`
+	Category	string		`json:"category"`
+	Frequency	string		`json:"frequency"`
+	Interval	int			`json:"interval"`
+	StartDate	string		`json:"startDate,omitempty"`
+	EndDate		string		`json:"endDate,omitempty"`
+}
+
+type addRecurringExpenseResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	RecurringId	string	`json:"recurringId"`
+}
+
+func HandleAddRecurringExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addRecurringExpenseRequestData := &addRecurringExpenseRequest{}
+		err := json.NewDecoder(r.Body).Decode(&addRecurringExpenseRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if addRecurringExpenseRequestData.Description == "" || addRecurringExpenseRequestData.Amount == "" || addRecurringExpenseRequestData.Frequency == "" {
+			http.Error(w, "\"Description\", \"Amount\" and \"Frequency\" are required!", http.StatusBadRequest)
+			return
+		}
+
+		addRecurringExpenseRequestData.Amount, addRecurringExpenseRequestData.Currency, err = NormalizeMoney(addRecurringExpenseRequestData.Amount, addRecurringExpenseRequestData.Currency)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		addRecurringExpenseRequestData.Type, err = NormalizeEntryType(addRecurringExpenseRequestData.Type, addRecurringExpenseRequestData.Amount)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if addRecurringExpenseRequestData.Type == "expense" && addRecurringExpenseRequestData.Category == "" {
+			http.Error(w, "\"Category\" is required for expenses!", http.StatusBadRequest)
+			return
+		}
+
+		addRecurringExpenseRequestData.Frequency, addRecurringExpenseRequestData.Interval, addRecurringExpenseRequestData.StartDate, addRecurringExpenseRequestData.EndDate, err = normalizeRecurringSchedule(
+			addRecurringExpenseRequestData.Frequency,
+			addRecurringExpenseRequestData.Interval,
+			addRecurringExpenseRequestData.StartDate,
+			addRecurringExpenseRequestData.EndDate,
+		)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		addRecurringExpenseRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "AddRecurringExpense", addRecurringExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					recurringId, _ := data["recurringId"].(string)
+
+					addRecurringExpenseResponseData := addRecurringExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						RecurringId: recurringId,
+					}
+
+					addRecurringExpenseResponseDataJSON, err := json.Marshal(addRecurringExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(addRecurringExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type updateRecurringExpenseRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	RecurringId	string		`json:"recurringId"`
+	Description	string		`json:"description"`
+	Type		string		`json:"type"`
+	Amount		json.Number	`json:"amount"`
+	Currency	string		`json:"currency"`
+	Category	string		`json:"category"`
+	Frequency	string		`json:"frequency"`
+	Interval	int			`json:"interval"`
+	StartDate	string		`json:"startDate,omitempty"`
+	EndDate		string		`json:"endDate,omitempty"`
+}
+
+type updateRecurringExpenseResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleUpdateRecurringExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		updateRecurringExpenseRequestData := &updateRecurringExpenseRequest{}
+		err := json.NewDecoder(r.Body).Decode(&updateRecurringExpenseRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if updateRecurringExpenseRequestData.RecurringId == "" || updateRecurringExpenseRequestData.Description == "" || updateRecurringExpenseRequestData.Amount == "" || updateRecurringExpenseRequestData.Frequency == "" {
+			http.Error(w, "\"RecurringId\", \"Description\", \"Amount\" and \"Frequency\" are required!", http.StatusBadRequest)
+			return
+		}
+
+		updateRecurringExpenseRequestData.Amount, updateRecurringExpenseRequestData.Currency, err = NormalizeMoney(updateRecurringExpenseRequestData.Amount, updateRecurringExpenseRequestData.Currency)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		updateRecurringExpenseRequestData.Type, err = NormalizeEntryType(updateRecurringExpenseRequestData.Type, updateRecurringExpenseRequestData.Amount)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if updateRecurringExpenseRequestData.Type == "expense" && updateRecurringExpenseRequestData.Category == "" {
+			http.Error(w, "\"Category\" is required for expenses!", http.StatusBadRequest)
+			return
+		}
+
+		updateRecurringExpenseRequestData.Frequency, updateRecurringExpenseRequestData.Interval, updateRecurringExpenseRequestData.StartDate, updateRecurringExpenseRequestData.EndDate, err = normalizeRecurringSchedule(
+			updateRecurringExpenseRequestData.Frequency,
+			updateRecurringExpenseRequestData.Interval,
+			updateRecurringExpenseRequestData.StartDate,
+			updateRecurringExpenseRequestData.EndDate,
+		)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		updateRecurringExpenseRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "UpdateRecurringExpense", updateRecurringExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					updateRecurringExpenseResponseData := updateRecurringExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					updateRecurringExpenseResponseDataJSON, err := json.Marshal(updateRecurringExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(updateRecurringExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type removeRecurringExpenseRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	RecurringId	string	`json:"recurringId"`
+}
+
+type removeRecurringExpenseResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRemoveRecurringExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		removeRecurringExpenseRequestData := &removeRecurringExpenseRequest{}
+		err := json.NewDecoder(r.Body).Decode(&removeRecurringExpenseRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if removeRecurringExpenseRequestData.RecurringId == "" {
+			http.Error(w, "\"RecurringId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		removeRecurringExpenseRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "RemoveRecurringExpense", removeRecurringExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					removeRecurringExpenseResponseData := removeRecurringExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					removeRecurringExpenseResponseDataJSON, err := json.Marshal(removeRecurringExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(removeRecurringExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense/internal/middlewares"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+var reportPeriods = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+type getReportRequest struct {
+	Action			string	`json:"action"`
+	UserId			string	`json:"userId"`
+	From			string	`json:"from,omitempty"`
+	To				string	`json:"to,omitempty"`
+	Type			string	`json:"type"`
+	GroupByCategory	bool	`json:"groupByCategory"`
+	Period			string	`json:"period,omitempty"`
+	BaseCurrency	string	`json:"baseCurrency"`
+}
+
+type getReportResponse struct {
+	Message			string		`json:"message"`
+	Success			bool		`json:"success"`
+	BaseCurrency	string		`json:"baseCurrency"`
+	Groups			interface{}	`json:"groups"`
+	Total			string		`json:"total"`
+	Count			float64		`json:"count"`
+	Average			string		`json:"average"`
+	MissingRates	bool		`json:"missingRates"`
+}
+
+func HandleGetReportRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getReportRequestData := &getReportRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "from" && key != "to" && key != "groupBy" && key != "type" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+
+		from, to, err := ParseDateRange(query.Get("from"), query.Get("to"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		getReportRequestData.From = from
+		getReportRequestData.To = to
+
+		getReportRequestData.Type = strings.ToLower(query.Get("type"))
+		if getReportRequestData.Type == "" {
+			getReportRequestData.Type = "expense"
+		}
+		if getReportRequestData.Type != "expense" && getReportRequestData.Type != "income" {
+			http.Error(w, "\"Type\" must be one of \"expense\" or \"income\"!", http.StatusBadRequest)
+			return
+		}
+
+		groupBy := query.Get("groupBy")
+		if groupBy == "" {
+			groupBy = "category"
+		}
+		for _, group := range strings.Split(groupBy, ",") {
+			group = strings.ToLower(strings.TrimSpace(group))
+			if group == "category" {
+				getReportRequestData.GroupByCategory = true
+				continue
+			}
+			if !reportPeriods[group] || getReportRequestData.Period != "" {
+				http.Error(w, "\"GroupBy\" must be \"category\" and/or one of \"day\", \"week\" or \"month\"!", http.StatusBadRequest)
+				return
+			}
+			getReportRequestData.Period = group
+		}
+		getReportRequestData.BaseCurrency = middlewares.GetBaseCurrencyFromRequest(r)
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getReportRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetReport", getReportRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					baseCurrency, _ := data["baseCurrency"].(string)
+					total, _ := data["total"].(string)
+					count, _ := data["count"].(float64)
+					average, _ := data["average"].(string)
+					missingRates, _ := data["missingRates"].(bool)
+
+					getReportResponseData := getReportResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						BaseCurrency: baseCurrency,
+						Groups: data["groups"],
+						Total: total,
+						Count: count,
+						Average: average,
+						MissingRates: missingRates,
+					}
+
+					getReportResponseDataJSON, err := json.Marshal(getReportResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getReportResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
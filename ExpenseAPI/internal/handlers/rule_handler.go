@@ -0,0 +1,568 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"expense/internal/middlewares"
+	"expense/internal/money"
+	"expense/internal/rules"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+const maxPatternLength = 200
+
+// ruleDefinition holds the fields shared by new and updated rules.
+type ruleDefinition struct {
+	Name		string		`json:"name"`
+	Priority	int			`json:"priority"`
+	Field		string		`json:"field"`
+	MatchType	string		`json:"matchType"`
+	Pattern		string		`json:"pattern"`
+	Currency	string		`json:"currency,omitempty"`
+	MinAmount	json.Number	`json:"minAmount,omitempty"`
+	MaxAmount	json.Number	`json:"maxAmount,omitempty"`
+	Category	string		`json:"category"`
+	Tags		[]string	`json:"tags"`
+}
+
+// normalizeRuleDefinition validates a rule. A rule needs at least one
+// condition and has to set a category or tags.
+func normalizeRuleDefinition(definition *ruleDefinition) error {
+	definition.Name = strings.TrimSpace(definition.Name)
+	if definition.Name == "" {
+		return errors.New("\"Name\" is required!")
+	}
+
+	definition.Field = strings.ToLower(definition.Field)
+	if definition.Field == "" {
+		definition.Field = "any"
+	}
+	if !rules.Fields[definition.Field] {
+		return errors.New("\"Field\" must be one of \"payee\", \"description\" or \"any\"!")
+	}
+	definition.MatchType = strings.ToLower(definition.MatchType)
+	if definition.MatchType == "" {
+		definition.MatchType = "contains"
+	}
+	if !rules.MatchTypes[definition.MatchType] {
+		return errors.New("\"MatchType\" must be either \"contains\" or \"regex\"!")
+	}
+	if len(definition.Pattern) > maxPatternLength {
+		return errors.New("\"Pattern\" must be at most 200 characters long!")
+	}
+	if definition.MatchType == "regex" {
+		if _, err := regexp.Compile(definition.Pattern); err != nil {
+			return errors.New("\"Pattern\" must be a valid regular expression!")
+		}
+	}
+
+	if definition.MinAmount != "" || definition.MaxAmount != "" {
+		if definition.Currency == "" {
+			definition.Currency = money.DefaultCurrency()
+		}
+		definition.Currency = strings.ToUpper(definition.Currency)
+		if !money.IsCurrency(definition.Currency) {
+			return errors.New("\"Currency\" must be an ISO 4217 currency code!")
+		}
+		for _, amount := range []json.Number{definition.MinAmount, definition.MaxAmount} {
+			if amount == "" {
+				continue
+			}
+			if _, err := money.Parse(amount.String(), definition.Currency); err != nil {
+				return err
+			}
+		}
+	} else {
+		definition.Currency = ""
+	}
+	if definition.Pattern == "" && definition.MinAmount == "" && definition.MaxAmount == "" {
+		return errors.New("\"Pattern\", \"MinAmount\" or \"MaxAmount\" is required!")
+	}
+
+	tags, err := NormalizeTags(definition.Tags)
+	if err != nil {
+		return err
+	}
+	definition.Tags = tags
+	if definition.Category == "" && len(definition.Tags) == 0 {
+		return errors.New("\"Category\" or \"Tags\" is required!")
+	}
+	return nil
+}
+
+type getRuleRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	RuleId	string	`json:"ruleId"`
+}
+
+type getRuleResponse struct {
+	Message	string		`json:"message"`
+	Success	bool		`json:"success"`
+	Rules	interface{}	`json:"rules"`
+}
+
+func HandleGetRuleRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getRuleRequestData := &getRuleRequest{}
+
+		query := r.URL.Query()
+		for key, _ := range query {
+			if key != "ruleId" {
+				http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+				return
+			}
+		}
+		getRuleRequestData.RuleId = query.Get("ruleId")
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getRuleRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetRule", getRuleRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getRuleResponseData := getRuleResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Rules: data["rules"],
+					}
+
+					getRuleResponseDataJSON, err := json.Marshal(getRuleResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getRuleResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type addRuleRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	ruleDefinition
+}
+
+type addRuleResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	RuleId	string	`json:"ruleId"`
+}
+
+func HandleAddRuleRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addRuleRequestData := &addRuleRequest{}
+		err := json.NewDecoder(r.Body).Decode(&addRuleRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		err = normalizeRuleDefinition(&addRuleRequestData.ruleDefinition)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		addRuleRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "AddRule", addRuleRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					ruleId, _ := data["ruleId"].(string)
+
+					addRuleResponseData := addRuleResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						RuleId: ruleId,
+					}
+
+					addRuleResponseDataJSON, err := json.Marshal(addRuleResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(addRuleResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type updateRuleRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	RuleId	string	`json:"ruleId"`
+	ruleDefinition
+}
+
+type updateRuleResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleUpdateRuleRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		updateRuleRequestData := &updateRuleRequest{}
+		err := json.NewDecoder(r.Body).Decode(&updateRuleRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if updateRuleRequestData.RuleId == "" {
+			http.Error(w, "\"RuleId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		err = normalizeRuleDefinition(&updateRuleRequestData.ruleDefinition)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		updateRuleRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "UpdateRule", updateRuleRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					updateRuleResponseData := updateRuleResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					updateRuleResponseDataJSON, err := json.Marshal(updateRuleResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(updateRuleResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type removeRuleRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	RuleId	string	`json:"ruleId"`
+}
+
+type removeRuleResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRemoveRuleRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		removeRuleRequestData := &removeRuleRequest{}
+		err := json.NewDecoder(r.Body).Decode(&removeRuleRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if removeRuleRequestData.RuleId == "" {
+			http.Error(w, "\"RuleId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		removeRuleRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "RemoveRule", removeRuleRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					removeRuleResponseData := removeRuleResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					removeRuleResponseDataJSON, err := json.Marshal(removeRuleResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(removeRuleResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type applyRulesRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	From		string	`json:"from,omitempty"`
+	To			string	`json:"to,omitempty"`
+	Overwrite	bool	`json:"overwrite"`
+}
+
+type applyRulesResponse struct {
+	Message	string		`json:"message"`
+	Success	bool		`json:"success"`
+	Matched	interface{}	`json:"matched"`
+	Updated	interface{}	`json:"updated"`
+	Skipped	interface{}	`json:"skipped"`
+}
+
+func HandleApplyRulesRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		applyRulesRequestData := &applyRulesRequest{}
+		err := json.NewDecoder(r.Body).Decode(&applyRulesRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		applyRulesRequestData.From, applyRulesRequestData.To, err = ParseDateRange(applyRulesRequestData.From, applyRulesRequestData.To)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		applyRulesRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "ApplyRules", applyRulesRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					applyRulesResponseData := applyRulesResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Matched: data["matched"],
+						Updated: data["updated"],
+						Skipped: data["skipped"],
+					}
+
+					applyRulesResponseDataJSON, err := json.Marshal(applyRulesResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(applyRulesResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
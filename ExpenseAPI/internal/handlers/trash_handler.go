@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expense/internal/middlewares"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+type getTrashRequest struct {
+	Action			string	`json:"action"`
+	UserId			string	`json:"userId"`
+	BaseCurrency	string	`json:"baseCurrency"`
+}
+
+type getTrashResponse struct {
+	Message		string		`json:"message"`
+	Success		bool		`json:"success"`
+	Expenses	interface{}	`json:"expenses"`
+}
+
+func HandleGetTrashRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		getTrashRequestData := &getTrashRequest{}
+
+		if len(r.URL.Query()) > 0 {
+			http.Error(w, "Invalid query parameter!", http.StatusBadRequest)
+			return
+		}
+		getTrashRequestData.BaseCurrency = middlewares.GetBaseCurrencyFromRequest(r)
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		getTrashRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "GetTrash", getTrashRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					getTrashResponseData := getTrashResponse{
+						Message: "Operation is successful!",
+						Success: true,
+						Expenses: data["expenses"],
+					}
+
+					getTrashResponseDataJSON, err := json.Marshal(getTrashResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(getTrashResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
+
+type restoreExpenseRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+}
+
+type restoreExpenseResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+}
+
+func HandleRestoreExpenseRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		restoreExpenseRequestData := &restoreExpenseRequest{}
+		err := json.NewDecoder(r.Body).Decode(&restoreExpenseRequestData)
+		if err != nil {
+			http.Error(w, "Format is invalid!", http.StatusBadRequest)
+			return
+		}
+
+		if restoreExpenseRequestData.ExpenseId == "" {
+			http.Error(w, "\"ExpenseId\" is required!", http.StatusBadRequest)
+			return
+		}
+
+		correlationId := uuid.New().String()
+
+		replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create queue!", http.StatusInternalServerError)
+			return
+		}
+
+		messages, err := ch.Consume(replyQueue.Name, "", true, false, false, false, nil)
+		if err != nil {
+			http.Error(w, "Failed to create consumer!", http.StatusInternalServerError)
+			return
+		}
+
+		userId, err := middlewares.GetUserIdFromRequest(r)
+		if err != nil {
+			http.Error(w, "Failed to get user id!", http.StatusInternalServerError)
+			return
+		}
+
+		restoreExpenseRequestData.UserId = userId
+		SendRequest(ch, "expenseQueue", "RestoreExpense", restoreExpenseRequestData, replyQueue.Name, correlationId)
+
+		for message := range messages {
+			if message.CorrelationId == correlationId {
+				serviceResponseData := serviceResponse{}
+				err := json.Unmarshal(message.Body, &serviceResponseData)
+				if err != nil {
+					http.Error(w, "Failed to convert service response!", http.StatusInternalServerError)
+					return
+				}
+
+				data := serviceResponseData.Data
+
+				success, successExists := data["success"].(bool)
+				if !successExists {
+					http.Error(w, "\"Success\" field is not found!", http.StatusInternalServerError)
+					return
+				}
+
+				if success {
+					restoreExpenseResponseData := restoreExpenseResponse{
+						Message: "Operation is successful!",
+						Success: true,
+					}
+
+					restoreExpenseResponseDataJSON, err := json.Marshal(restoreExpenseResponseData)
+					if err != nil {
+						http.Error(w, "Failed to convert response!", http.StatusInternalServerError)
+						return
+					}
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write(restoreExpenseResponseDataJSON)
+				} else {
+					WriteServiceError(w, data)
+				}
+				return
+			}
+		}
+		http.Error(w, "No response is received!", http.StatusRequestTimeout)
+	}
+}
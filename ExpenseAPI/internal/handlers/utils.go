@@ -2,7 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"expense/internal/money"
 	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/streadway/amqp"
 )
@@ -38,3 +44,197 @@ type serviceResponse struct {
 	Action  string `json:"action"`
 	Data    map[string]interface{} `json:"data"`
 }
+
+// ParseDate accepts "YYYY-MM-DD" or RFC 3339; the flag reports a date-only value.
+func ParseDate(value string) (time.Time, bool, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, true, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return date.UTC(), false, nil
+}
+
+// ParseDateRange parses the "from" and "to" query parameters into RFC 3339
+// bounds. A date-only "to" includes the whole day, so the bound becomes the
+// next midnight.
+func ParseDateRange(from string, to string) (string, string, error) {
+	var fromDate, toDate time.Time
+	if from != "" {
+		parsedFrom, _, err := ParseDate(from)
+		if err != nil {
+			return "", "", errors.New("\"From\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!")
+		}
+		fromDate = parsedFrom
+		from = fromDate.Format(time.RFC3339)
+	}
+	if to != "" {
+		parsedTo, dateOnly, err := ParseDate(to)
+		if err != nil {
+			return "", "", errors.New("\"To\" must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!")
+		}
+		toDate = parsedTo
+		if dateOnly {
+			toDate = toDate.AddDate(0, 0, 1)
+		}
+		to = toDate.Format(time.RFC3339)
+	}
+	if !fromDate.IsZero() && !toDate.IsZero() && !fromDate.Before(toDate) {
+		return "", "", errors.New("\"From\" must be earlier than \"To\"!")
+	}
+	return from, to, nil
+}
+
+// NormalizeMoney validates a decimal amount against its currency and returns
+// both in canonical form, falling back to the default currency.
+func NormalizeMoney(amount json.Number, currency string) (json.Number, string, error) {
+	if currency == "" {
+		currency = money.DefaultCurrency()
+	}
+	currency = strings.ToUpper(currency)
+	if !money.IsCurrency(currency) {
+		return "", "", errors.New("\"Currency\" must be an ISO 4217 currency code!")
+	}
+	minor, err := money.Parse(amount.String(), currency)
+	if err != nil {
+		return "", "", err
+	}
+	if minor == 0 {
+		return "", "", errors.New("\"Amount\" must not be zero!")
+	}
+	return json.Number(money.Format(minor, currency)), currency, nil
+}
+
+// WriteServiceError reports a failed service response. Responses without an
+// explicit status keep the historical 401 behaviour.
+func WriteServiceError(w http.ResponseWriter, data map[string]interface{}) {
+	status, statusExists := data["status"].(float64)
+	if !statusExists || status == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	message, _ := data["message"].(string)
+	http.Error(w, message, int(status))
+}
+
+const (
+	maxTags		= 20
+	maxTagLength	= 50
+)
+
+// NormalizeTags trims and lowercases tags and drops duplicates, so "Trip-Berlin"
+// and "trip-berlin" are the same label.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, errors.New("\"Tags\" must not contain empty values!")
+		}
+		if len(tag) > maxTagLength {
+			return nil, errors.New("\"Tags\" must be at most 50 characters long!")
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, errors.New("At most 20 \"Tags\" can be used!")
+	}
+	return normalized, nil
+}
+
+var entryTypes = map[string]bool{
+	"expense":  true,
+	"income":   true,
+	"transfer": true,
+}
+
+// NormalizeEntryType validates the type of an entry, which defaults to an
+// expense. Amounts are always positive; the type tells the direction.
+func NormalizeEntryType(entryType string, amount json.Number) (string, error) {
+	entryType = strings.ToLower(entryType)
+	if entryType == "" {
+		entryType = "expense"
+	}
+	if !entryTypes[entryType] {
+		return "", errors.New("\"Type\" must be one of \"expense\", \"income\" or \"transfer\"!")
+	}
+	if strings.HasPrefix(amount.String(), "-") {
+		return "", errors.New("\"Amount\" must be positive, use \"Type\" to record income!")
+	}
+	return entryType, nil
+}
+
+// ValidateEntryAccounts checks the accounts of an entry. A transfer moves money
+// from "AccountId" to "ToAccountId", other entries only book on "AccountId".
+func ValidateEntryAccounts(entryType string, accountId string, toAccountId string) error {
+	if entryType == "transfer" {
+		if accountId == "" || toAccountId == "" {
+			return errors.New("\"AccountId\" and \"ToAccountId\" are required for transfers!")
+		}
+		if accountId == toAccountId {
+			return errors.New("\"AccountId\" and \"ToAccountId\" must be different!")
+		}
+		return nil
+	}
+	if toAccountId != "" {
+		return errors.New("\"ToAccountId\" can only be used for transfers!")
+	}
+	return nil
+}
+
+const maxPayeeLength = 100
+
+// NormalizePayee collapses the whitespace of a payee so that rules and
+// filters see the same merchant name however it was typed.
+func NormalizePayee(payee string) (string, error) {
+	payee = strings.Join(strings.Fields(payee), " ")
+	if len(payee) > maxPayeeLength {
+		return "", errors.New("\"Payee\" must be at most 100 characters long!")
+	}
+	return payee, nil
+}
+
+const maxNotesLength = 1000
+
+// NormalizeNotes trims the free-form notes of an entry.
+func NormalizeNotes(notes string) (string, error) {
+	notes = strings.TrimSpace(notes)
+	if len(notes) > maxNotesLength {
+		return "", errors.New("\"Notes\" must be at most 1000 characters long!")
+	}
+	return notes, nil
+}
+
+var errIfMatchRequired = errors.New("\"If-Match\" header with the ETag of the expense is required!")
+
+// FormatETag returns the strong ETag of a version of an entry.
+func FormatETag(version int64) string {
+	return "\"" + strconv.FormatInt(version, 10) + "\""
+}
+
+// ParseIfMatch reads the version a write is based on from an If-Match header
+// holding the ETag of an earlier read. "*" matches any version and yields nil.
+func ParseIfMatch(header string) (*int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil, errIfMatchRequired
+	}
+	if header == "*" {
+		return nil, nil
+	}
+	if len(header) < 2 || !strings.HasPrefix(header, "\"") || !strings.HasSuffix(header, "\"") {
+		return nil, errors.New("\"If-Match\" must be an ETag returned for the expense!")
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version < 0 {
+		return nil, errors.New("\"If-Match\" must be an ETag returned for the expense!")
+	}
+	return &version, nil
+}
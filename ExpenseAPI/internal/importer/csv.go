@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"expense/internal/money"
+	"io"
+	"strings"
+)
+
+// Mapping names the CSV columns, by their header, that hold the fields of an entry.
+type Mapping struct {
+	Date        string `json:"date"`
+	Amount      string `json:"amount"`
+	Description string `json:"description"`
+	Payee       string `json:"payee"`
+	Category    string `json:"category"`
+	Currency    string `json:"currency"`
+}
+
+type CSVOptions struct {
+	Mapping        Mapping
+	Delimiter      rune
+	DateFormat     string
+	Currency       string
+	DecimalComma   bool
+	SignConvention string
+}
+
+type csvColumns struct {
+	date        int
+	amount      int
+	description int
+	payee       int
+	category    int
+	currency    int
+}
+
+func findColumn(header []string, name string, required bool) (int, error) {
+	if name == "" {
+		if required {
+			return -1, errors.New("Mapping is incomplete!")
+		}
+		return -1, nil
+	}
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), strings.TrimSpace(name)) {
+			return i, nil
+		}
+	}
+	return -1, errors.New("Column \"" + name + "\" is not found!")
+}
+
+func cell(record []string, column int) string {
+	if column < 0 || column >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[column])
+}
+
+// ParseCSV reads the entries of a CSV file with a header row. Rows that cannot
+// be read are reported with their line number instead of failing the import;
+// an error is only returned when the file itself cannot be used.
+func ParseCSV(reader io.Reader, options CSVOptions) ([]Row, []RowError, error) {
+	csvReader := csv.NewReader(reader)
+	if options.Delimiter != 0 {
+		csvReader.Comma = options.Delimiter
+	}
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("File is empty!")
+	}
+	if err != nil {
+		return nil, nil, errors.New("File is not a valid CSV file!")
+	}
+	// Spreadsheet programs like to start UTF-8 files with a byte order mark.
+	if len(header) != 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	columns := csvColumns{}
+	if columns.date, err = findColumn(header, options.Mapping.Date, true); err != nil {
+		return nil, nil, err
+	}
+	if columns.amount, err = findColumn(header, options.Mapping.Amount, true); err != nil {
+		return nil, nil, err
+	}
+	if columns.description, err = findColumn(header, options.Mapping.Description, true); err != nil {
+		return nil, nil, err
+	}
+	if columns.payee, err = findColumn(header, options.Mapping.Payee, false); err != nil {
+		return nil, nil, err
+	}
+	if columns.category, err = findColumn(header, options.Mapping.Category, false); err != nil {
+		return nil, nil, err
+	}
+	if columns.currency, err = findColumn(header, options.Mapping.Currency, false); err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([]Row, 0)
+	rowErrors := make([]RowError, 0)
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseError *csv.ParseError
+			if errors.As(err, &parseError) {
+				rowErrors = append(rowErrors, RowError{Line: parseError.StartLine, Message: "Row is not valid CSV!"})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := csvReader.FieldPos(0)
+		if len(rows)+len(rowErrors) >= MaxRows {
+			return nil, nil, errors.New("File must have at most 5000 rows!")
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		row, err := csvRow(record, columns, options)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Message: err.Error()})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+func csvRow(record []string, columns csvColumns, options CSVOptions) (Row, error) {
+	row := Row{
+		Description: normalizeText(cell(record, columns.description)),
+		Payee:       normalizeText(cell(record, columns.payee)),
+		Category:    cell(record, columns.category),
+		Currency:    strings.ToUpper(cell(record, columns.currency)),
+	}
+	if row.Description == "" {
+		row.Description = row.Payee
+	}
+	if row.Description == "" {
+		return row, errors.New("Description is empty!")
+	}
+	if row.Currency == "" {
+		row.Currency = options.Currency
+	}
+	if !money.IsCurrency(row.Currency) {
+		return row, errors.New("Currency must be an ISO 4217 currency code!")
+	}
+
+	date, err := parseDate(cell(record, columns.date), options.DateFormat)
+	if err != nil {
+		return row, err
+	}
+	row.Date = date
+
+	amount, err := parseAmount(cell(record, columns.amount), row.Currency, options.DecimalComma)
+	if err != nil {
+		return row, err
+	}
+	if amount == 0 {
+		return row, errors.New("Amount must not be zero!")
+	}
+	row.Type, amount = entryFromAmount(amount, options.SignConvention)
+	row.Amount = money.Format(amount, row.Currency)
+	return row, nil
+}
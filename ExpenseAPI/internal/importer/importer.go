@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"errors"
+	"expense/internal/money"
+	"strings"
+	"time"
+)
+
+// MaxRows bounds the size of a single import.
+const MaxRows = 5000
+
+// Row is an entry read from an imported file. Amounts are positive decimals in
+// canonical form; the type tells the direction of the money.
+type Row struct {
+	Line        int       `json:"line"`
+	Date        time.Time `json:"date"`
+	Type        string    `json:"type"`
+	Amount      string    `json:"amount"`
+	Currency    string    `json:"currency"`
+	Description string    `json:"description"`
+	Payee       string    `json:"payee"`
+	Category    string    `json:"category"`
+	ExternalId  string    `json:"externalId,omitempty"`
+}
+
+type RowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Sign conventions of bank exports. Most banks export debits as negative
+// amounts, while expense trackers usually list them as positive ones.
+const (
+	PositiveExpense = "positive-expense"
+	NegativeExpense = "negative-expense"
+)
+
+var SignConventions = map[string]bool{
+	PositiveExpense: true,
+	NegativeExpense: true,
+}
+
+// DateFormats maps the supported date formats to their Go layouts. The
+// layouts accept days and months with or without a leading zero.
+var DateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-1-2",
+	"DD.MM.YYYY": "2.1.2006",
+	"DD/MM/YYYY": "2/1/2006",
+	"MM/DD/YYYY": "1/2/2006",
+}
+
+func parseDate(value string, dateFormat string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if dateFormat == "" {
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			return date, nil
+		}
+		if date, err := time.Parse(time.RFC3339, value); err == nil {
+			return date.UTC(), nil
+		}
+		return time.Time{}, errors.New("Date must be a date (YYYY-MM-DD) or an RFC 3339 timestamp!")
+	}
+	date, err := time.Parse(DateFormats[dateFormat], value)
+	if err != nil {
+		return time.Time{}, errors.New("Date does not match the format " + dateFormat + "!")
+	}
+	return date, nil
+}
+
+// parseAmount reads an amount as banks export it: with thousands separators,
+// a decimal comma or parentheses around negative values.
+func parseAmount(value string, currency string, decimalComma bool) (int64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+	if decimalComma {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.ReplaceAll(value, ",", ".")
+	} else {
+		value = strings.ReplaceAll(value, ",", "")
+	}
+
+	amount, err := money.Parse(value, currency)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// entryFromAmount derives the type of an entry from the sign of its amount.
+func entryFromAmount(amount int64, signConvention string) (string, int64) {
+	if signConvention == NegativeExpense {
+		amount = -amount
+	}
+	if amount < 0 {
+		return "income", -amount
+	}
+	return "expense", amount
+}
+
+func normalizeText(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,202 @@
+package importer
+
+import (
+	"errors"
+	"expense/internal/money"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type ofxTransaction struct {
+	line     int
+	fields   map[string]string
+	currency string
+	account  string
+}
+
+// ParseOFX reads the transactions of an OFX statement. Both the SGML variant
+// of OFX 1.x, where leaf elements are not closed, and the XML variant of OFX
+// 2.x are supported. Transactions get the statement's FITID as external id, so
+// a statement imported twice is recognized.
+func ParseOFX(reader io.Reader, currency string) ([]Row, []RowError, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	content := decodeText(data)
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start < 0 {
+		return nil, nil, errors.New("File is not a valid OFX file!")
+	}
+
+	transactions := make([]ofxTransaction, 0)
+	var current *ofxTransaction
+	statementCurrency, account := currency, ""
+	line, counted := 1, 0
+	for position := start; position < len(content); {
+		open := strings.IndexByte(content[position:], '<')
+		if open < 0 {
+			break
+		}
+		open += position
+		line += strings.Count(content[counted:open], "\n")
+		counted = open
+		end := strings.IndexByte(content[open:], '>')
+		if end < 0 {
+			return nil, nil, errors.New("File is not a valid OFX file!")
+		}
+		end += open
+
+		tag := strings.ToUpper(strings.TrimSpace(content[open+1 : end]))
+		next := strings.IndexByte(content[end+1:], '<')
+		if next < 0 {
+			next = len(content)
+		} else {
+			next += end + 1
+		}
+		value := strings.TrimSpace(html.UnescapeString(content[end+1 : next]))
+		position = next
+
+		switch {
+		case tag == "STMTTRN":
+			current = &ofxTransaction{line: line, fields: make(map[string]string), currency: statementCurrency, account: account}
+		case tag == "/STMTTRN":
+			if current != nil {
+				transactions = append(transactions, *current)
+				current = nil
+			}
+		case strings.HasPrefix(tag, "/") || strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!"):
+		case current != nil:
+			if _, exists := current.fields[tag]; !exists {
+				current.fields[tag] = value
+			}
+		case tag == "CURDEF" && value != "":
+			statementCurrency = strings.ToUpper(value)
+		case tag == "ACCTID":
+			account = value
+		}
+		if len(transactions) > MaxRows {
+			return nil, nil, errors.New("File must have at most 5000 transactions!")
+		}
+	}
+	if current != nil {
+		return nil, nil, errors.New("File is not a valid OFX file!")
+	}
+
+	rows := make([]Row, 0, len(transactions))
+	rowErrors := make([]RowError, 0)
+	for _, transaction := range transactions {
+		row, err := ofxRow(transaction)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: transaction.line, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+func ofxRow(transaction ofxTransaction) (Row, error) {
+	fields := transaction.fields
+	row := Row{
+		Line:        transaction.line,
+		Payee:       normalizeText(fields["NAME"]),
+		Description: normalizeText(fields["MEMO"]),
+		Currency:    transaction.currency,
+	}
+	if row.Description == "" {
+		row.Description = row.Payee
+	}
+	if row.Description == "" {
+		row.Description = normalizeText(fields["TRNTYPE"])
+	}
+	if row.Description == "" {
+		return row, errors.New("Description is empty!")
+	}
+	if !money.IsCurrency(row.Currency) {
+		return row, errors.New("Currency must be an ISO 4217 currency code!")
+	}
+
+	fitId := fields["FITID"]
+	if fitId == "" {
+		return row, errors.New("FITID is missing!")
+	}
+	row.ExternalId = "ofx:" + transaction.account + ":" + fitId
+
+	date, err := parseOFXDate(fields["DTPOSTED"])
+	if err != nil {
+		return row, err
+	}
+	row.Date = date
+
+	value := fields["TRNAMT"]
+	decimalComma := strings.Contains(value, ",") && !strings.Contains(value, ".")
+	amount, err := parseAmount(value, row.Currency, decimalComma)
+	if err != nil {
+		return row, err
+	}
+	if amount == 0 {
+		return row, errors.New("Amount must not be zero!")
+	}
+	// Debits are negative in OFX.
+	row.Type, amount = entryFromAmount(amount, NegativeExpense)
+	row.Amount = money.Format(amount, row.Currency)
+	return row, nil
+}
+
+// parseOFXDate reads an OFX datetime such as "20240131", "20240131120000" or
+// "20240131120000.000[-5:EST]". Without a time zone the value is in UTC.
+func parseOFXDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	zone := ""
+	if open := strings.IndexByte(value, '['); open >= 0 {
+		zone = strings.TrimSuffix(value[open+1:], "]")
+		value = value[:open]
+	}
+	if dot := strings.IndexByte(value, '.'); dot >= 0 {
+		value = value[:dot]
+	}
+
+	var date time.Time
+	var err error
+	switch len(value) {
+	case 8:
+		return time.Parse("20060102", value)
+	case 12:
+		date, err = time.Parse("200601021504", value)
+	case 14:
+		date, err = time.Parse("20060102150405", value)
+	default:
+		return time.Time{}, errors.New("Date is not a valid OFX date!")
+	}
+	if err != nil {
+		return time.Time{}, errors.New("Date is not a valid OFX date!")
+	}
+
+	if zone != "" {
+		offset, _, _ := strings.Cut(zone, ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, errors.New("Date is not a valid OFX date!")
+		}
+		date = date.Add(-time.Duration(hours * float64(time.Hour)))
+	}
+	return date.UTC(), nil
+}
+
+// decodeText returns the file as UTF-8. Older exports are often in a
+// single-byte charset; their bytes are read as Latin-1.
+func decodeText(data []byte) string {
+	if utf8.Valid(data) {
+		return strings.TrimPrefix(string(data), "\ufeff")
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
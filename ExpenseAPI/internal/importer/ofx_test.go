@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+CHARSET:1252
+
+<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>EUR
+<BANKACCTFROM>
+<BANKID>100
+<ACCTID>12345
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240131120000.000[-5:EST]
+<TRNAMT>-12.50
+<FITID>T1
+<NAME>Corner  Bakery
+<MEMO>Bread &amp; rolls
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240201
+<TRNAMT>1500.00
+<FITID>T2
+<NAME>Employer
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE"?>
+<OFX>
+  <BANKMSGSRSV1>
+    <STMTTRNRS>
+      <STMTRS>
+        <CURDEF>EUR</CURDEF>
+        <BANKACCTFROM>
+          <BANKID>100</BANKID>
+          <ACCTID>12345</ACCTID>
+          <ACCTTYPE>CHECKING</ACCTTYPE>
+        </BANKACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240131120000.000[-5:EST]</DTPOSTED>
+            <TRNAMT>-12.50</TRNAMT>
+            <FITID>T1</FITID>
+            <NAME>Corner  Bakery</NAME>
+            <MEMO>Bread &amp; rolls</MEMO>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>CREDIT</TRNTYPE>
+            <DTPOSTED>20240201</DTPOSTED>
+            <TRNAMT>1500.00</TRNAMT>
+            <FITID>T2</FITID>
+            <NAME>Employer</NAME>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </STMTRS>
+    </STMTTRNRS>
+  </BANKMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	want := []Row{
+		{
+			Date:        time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC),
+			Type:        "expense",
+			Amount:      "12.50",
+			Currency:    "EUR",
+			Description: "Bread & rolls",
+			Payee:       "Corner Bakery",
+			ExternalId:  "ofx:12345:T1",
+		},
+		{
+			Date:        time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			Type:        "income",
+			Amount:      "1500.00",
+			Currency:    "EUR",
+			Description: "Employer",
+			Payee:       "Employer",
+			ExternalId:  "ofx:12345:T2",
+		},
+	}
+	tests := []struct {
+		name      string
+		statement string
+	}{
+		{name: "SGML", statement: sgmlStatement},
+		{name: "XML", statement: xmlStatement},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, rowErrors, err := ParseOFX(strings.NewReader(test.statement), "USD")
+			if err != nil {
+				t.Fatalf("ParseOFX() returned %v", err)
+			}
+			if len(rowErrors) != 0 {
+				t.Fatalf("ParseOFX() returned row errors %v", rowErrors)
+			}
+			if len(rows) != len(want) {
+				t.Fatalf("ParseOFX() returned %d rows, want %d", len(rows), len(want))
+			}
+			for i := range rows {
+				// Line numbers differ between the two layouts.
+				rows[i].Line = 0
+				if !reflect.DeepEqual(rows[i], want[i]) {
+					t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseOFXInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+	}{
+		{name: "no OFX element", statement: "OFXHEADER:100\n"},
+		{name: "unterminated tag", statement: "<OFX><STMTTRN><TRNAMT"},
+		{name: "unclosed transaction", statement: "<OFX><STMTTRN><TRNAMT>1.00"},
+	}
+	for _, test := range tests {
+		if _, _, err := ParseOFX(strings.NewReader(test.statement), "USD"); err == nil {
+			t.Errorf("%s: ParseOFX() returned no error", test.name)
+		}
+	}
+}
+
+func TestParseOFXDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "20240131", want: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "202401311230", want: time.Date(2024, 1, 31, 12, 30, 0, 0, time.UTC)},
+		{value: "20240131123045", want: time.Date(2024, 1, 31, 12, 30, 45, 0, time.UTC)},
+		{value: "20240131123045.123", want: time.Date(2024, 1, 31, 12, 30, 45, 0, time.UTC)},
+		{value: "20240131120000.000[-5:EST]", want: time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC)},
+		{value: "20240131120000[-3.5]", want: time.Date(2024, 1, 31, 15, 30, 0, 0, time.UTC)},
+		{value: "20240131120000[+5.75:NPT]", want: time.Date(2024, 1, 31, 6, 15, 0, 0, time.UTC)},
+		{value: "20240131230000[-8:PST]", want: time.Date(2024, 2, 1, 7, 0, 0, 0, time.UTC)},
+		{value: "20240131120000[0:GMT]", want: time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{value: " 20240131 ", want: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "", wantErr: true},
+		{value: "2024013", wantErr: true},
+		{value: "20241331", wantErr: true},
+		{value: "20240131250000", wantErr: true},
+		{value: "20240131120000[EST]", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseOFXDate(test.value)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseOFXDate(%q) = %v, want an error", test.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseOFXDate(%q) returned %v", test.value, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("parseOFXDate(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
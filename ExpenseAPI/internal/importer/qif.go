@@ -0,0 +1,206 @@
+package importer
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"expense/internal/money"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QIF files carry no date format, and the separators vary between programs,
+// so only the order of day, month and year is taken from the date format.
+// Quicken writes US dates.
+var qifDateOrders = map[string]string{
+	"":           "MDY",
+	"MM/DD/YYYY": "MDY",
+	"DD/MM/YYYY": "DMY",
+	"DD.MM.YYYY": "DMY",
+	"YYYY-MM-DD": "YMD",
+}
+
+// Transactions of these account types can be imported; investment accounts cannot.
+var qifTypes = map[string]bool{
+	"bank":  true,
+	"cash":  true,
+	"ccard": true,
+	"oth a": true,
+	"oth l": true,
+}
+
+type qifRecord struct {
+	line   int
+	fields map[byte]string
+}
+
+// ParseQIF reads the transactions of a QIF file. QIF has no transaction ids,
+// so the external id of a transaction is derived from its date, amount, payee
+// and memo, counting repeated identical transactions.
+func ParseQIF(reader io.Reader, currency string, dateFormat string, decimalComma bool) ([]Row, []RowError, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !money.IsCurrency(currency) {
+		return nil, nil, errors.New("Currency must be an ISO 4217 currency code!")
+	}
+
+	records := make([]qifRecord, 0)
+	rowErrors := make([]RowError, 0)
+	scanner := bufio.NewScanner(strings.NewReader(decodeText(data)))
+	supported := false
+	headerFound := false
+	current := qifRecord{fields: make(map[byte]string)}
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "!") {
+			header := strings.ToLower(strings.TrimSpace(text[1:]))
+			if strings.HasPrefix(header, "type:") {
+				headerFound = true
+				supported = qifTypes[strings.TrimSpace(strings.TrimPrefix(header, "type:"))]
+			} else if header == "account" {
+				// Account lists are followed by their own records; they hold no transactions.
+				supported = false
+			}
+			continue
+		}
+		if text == "^" {
+			if len(current.fields) != 0 {
+				if supported {
+					records = append(records, current)
+				} else if headerFound {
+					rowErrors = append(rowErrors, RowError{Line: current.line, Message: "Only bank, cash and credit card transactions can be imported!"})
+				}
+			}
+			current = qifRecord{fields: make(map[byte]string)}
+			continue
+		}
+		if len(current.fields) == 0 {
+			current.line = line
+		}
+		// Split lines (S, E, $) repeat; only the first value of each field is kept.
+		if _, exists := current.fields[text[0]]; !exists {
+			current.fields[text[0]] = strings.TrimSpace(text[1:])
+		}
+		if len(records)+len(rowErrors) > MaxRows {
+			return nil, nil, errors.New("File must have at most 5000 transactions!")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, errors.New("File is not a valid QIF file!")
+	}
+	if !headerFound {
+		return nil, nil, errors.New("File is not a valid QIF file!")
+	}
+
+	rows := make([]Row, 0, len(records))
+	occurrences := make(map[string]int)
+	for _, record := range records {
+		row, err := qifRow(record, currency, qifDateOrders[dateFormat], decimalComma)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: record.line, Message: err.Error()})
+			continue
+		}
+
+		key := strings.Join([]string{row.Date.Format("2006-01-02"), row.Type, row.Amount, row.Payee, record.fields['M']}, "|")
+		occurrences[key]++
+		hash := sha1.Sum([]byte(key + "|" + strconv.Itoa(occurrences[key])))
+		row.ExternalId = "qif:" + hex.EncodeToString(hash[:])
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+func qifRow(record qifRecord, currency string, dateOrder string, decimalComma bool) (Row, error) {
+	fields := record.fields
+	row := Row{
+		Line:        record.line,
+		Payee:       normalizeText(fields['P']),
+		Description: normalizeText(fields['M']),
+		Category:    qifCategory(fields['L']),
+		Currency:    currency,
+	}
+	if row.Description == "" {
+		row.Description = row.Payee
+	}
+	if row.Description == "" {
+		return row, errors.New("Description is empty!")
+	}
+
+	date, err := parseQIFDate(fields['D'], dateOrder)
+	if err != nil {
+		return row, err
+	}
+	row.Date = date
+
+	value := fields['T']
+	if value == "" {
+		value = fields['U']
+	}
+	amount, err := parseAmount(value, currency, decimalComma)
+	if err != nil {
+		return row, err
+	}
+	if amount == 0 {
+		return row, errors.New("Amount must not be zero!")
+	}
+	// Payments are negative in QIF.
+	row.Type, amount = entryFromAmount(amount, NegativeExpense)
+	row.Amount = money.Format(amount, currency)
+	return row, nil
+}
+
+// qifCategory maps a QIF category such as "Food:Groceries/Vacation" to the
+// innermost category name. Transfers name an account in brackets instead.
+func qifCategory(value string) string {
+	if strings.HasPrefix(value, "[") {
+		return ""
+	}
+	value, _, _ = strings.Cut(value, "/")
+	if colon := strings.LastIndexByte(value, ':'); colon >= 0 {
+		value = value[colon+1:]
+	}
+	return strings.TrimSpace(value)
+}
+
+// parseQIFDate reads dates such as "1/31/2024", "01/31'24" or "31.01.2024".
+// Two-digit years from 70 on are in the 1900s.
+func parseQIFDate(value string, dateOrder string) (time.Time, error) {
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsDigit(r)
+	})
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("Date is not a valid QIF date!")
+	}
+
+	numbers := make(map[byte]int)
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, errors.New("Date is not a valid QIF date!")
+		}
+		numbers[dateOrder[i]] = number
+	}
+	year := numbers['Y']
+	if year < 70 {
+		year += 2000
+	} else if year < 100 {
+		year += 1900
+	}
+
+	date := time.Date(year, time.Month(numbers['M']), numbers['D'], 0, 0, 0, 0, time.UTC)
+	if date.Year() != year || int(date.Month()) != numbers['M'] || date.Day() != numbers['D'] {
+		return time.Time{}, errors.New("Date is not a valid QIF date!")
+	}
+	return date, nil
+}
@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const qifStatement = `!Type:Bank
+D1/31/2024
+T-12.50
+PCorner Bakery
+MBread
+LFood:Groceries
+^
+D1/31'24
+T-12.50
+PCorner Bakery
+MBread
+LFood:Groceries
+^
+D02/01/2024
+U1,500.00
+PEmployer
+L[Savings]
+^
+D2/30/2024
+T-3.00
+PNowhere
+^
+`
+
+func TestParseQIF(t *testing.T) {
+	rows, rowErrors, err := ParseQIF(strings.NewReader(qifStatement), "USD", "", false)
+	if err != nil {
+		t.Fatalf("ParseQIF() returned %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("ParseQIF() returned %d rows, want 3", len(rows))
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 19 {
+		t.Fatalf("ParseQIF() returned row errors %v, want one on line 19", rowErrors)
+	}
+
+	tests := []struct {
+		date     time.Time
+		rowType  string
+		amount   string
+		payee    string
+		category string
+	}{
+		{date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), rowType: "expense", amount: "12.50", payee: "Corner Bakery", category: "Groceries"},
+		{date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), rowType: "expense", amount: "12.50", payee: "Corner Bakery", category: "Groceries"},
+		{date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), rowType: "income", amount: "1500.00", payee: "Employer", category: ""},
+	}
+	for i, test := range tests {
+		row := rows[i]
+		if !row.Date.Equal(test.date) || row.Type != test.rowType || row.Amount != test.amount || row.Payee != test.payee || row.Category != test.category {
+			t.Errorf("row %d = %+v, want %+v", i, row, test)
+		}
+	}
+	// Identical transactions on the same day must still get distinct ids.
+	if rows[0].ExternalId == rows[1].ExternalId {
+		t.Errorf("repeated transactions share the external id %q", rows[0].ExternalId)
+	}
+}
+
+func TestParseQIFDate(t *testing.T) {
+	tests := []struct {
+		value     string
+		dateOrder string
+		want      time.Time
+		wantErr   bool
+	}{
+		{value: "1/31/2024", dateOrder: "MDY", want: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "01/31'24", dateOrder: "MDY", want: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "12/31/99", dateOrder: "MDY", want: time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "31.01.2024", dateOrder: "DMY", want: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-31", dateOrder: "YMD", want: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "2/30/2024", dateOrder: "MDY", wantErr: true},
+		{value: "31/01/2024", dateOrder: "MDY", wantErr: true},
+		{value: "1/31", dateOrder: "MDY", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseQIFDate(test.value, test.dateOrder)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseQIFDate(%q, %q) = %v, want an error", test.value, test.dateOrder, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseQIFDate(%q, %q) returned %v", test.value, test.dateOrder, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("parseQIFDate(%q, %q) = %v, want %v", test.value, test.dateOrder, got, test.want)
+		}
+	}
+}
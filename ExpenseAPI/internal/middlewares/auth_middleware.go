@@ -2,6 +2,7 @@ package middlewares
 
 import (
 	"context"
+	"expense/internal/money"
 	"net/http"
 	"strings"
 	"errors"
@@ -74,6 +75,31 @@ func GetUserIdFromRequest(r *http.Request) (string, error) {
 	return userId, nil
 }
 
+func GetBaseCurrencyFromRequest(r *http.Request) string {
+	tokenString := extractToken(r)
+	if tokenString == "" {
+		return money.DefaultCurrency()
+	}
+
+	jwtSecret := os.Getenv("JWT_SECRET")
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtSecret), nil
+	})
+	if err != nil {
+		return money.DefaultCurrency()
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return money.DefaultCurrency()
+	}
+	// Tokens issued before users had a base currency carry no such claim.
+	baseCurrency, ok := claims["baseCurrency"].(string)
+	if !ok || !money.IsCurrency(baseCurrency) {
+		return money.DefaultCurrency()
+	}
+	return baseCurrency
+}
+
 func extractToken(r *http.Request) string {
 	token := r.Header.Get("Authorization")
 	if token == "" {
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type Account struct {
+	AccountId		string		`json:"accountId" bson:"accountId"`
+	UserId			string		`json:"userId" bson:"userId"`
+	Name			string		`json:"name" bson:"name"`
+	Type			string		`json:"type" bson:"type"`
+	Currency		string		`json:"currency" bson:"currency"`
+	OpeningBalance	int64		`json:"openingBalance" bson:"openingBalance"`
+	CreatedAt		time.Time	`json:"createdAt" bson:"createdAt"`
+	UpdatedAt		time.Time	`json:"updatedAt" bson:"updatedAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type Budget struct {
+	BudgetId	string		`json:"budgetId" bson:"budgetId"`
+	UserId		string		`json:"userId" bson:"userId"`
+	Category	string		`json:"category" bson:"category"`
+	Amount		int64		`json:"amount" bson:"amount"`
+	Currency	string		`json:"currency" bson:"currency"`
+	Period		string		`json:"period" bson:"period"`
+	CreatedAt	time.Time	`json:"createdAt" bson:"createdAt"`
+	UpdatedAt	time.Time	`json:"updatedAt" bson:"updatedAt"`
+}
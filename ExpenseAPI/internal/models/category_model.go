@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type Category struct {
+	CategoryId		string		`json:"categoryId" bson:"categoryId"`
+	UserId			string		`json:"userId" bson:"userId"`
+	Name			string		`json:"name" bson:"name"`
+	NormalizedName	string		`json:"-" bson:"normalizedName"`
+	ParentId		string		`json:"parentId" bson:"parentId"`
+	Color			string		`json:"color" bson:"color"`
+	Icon			string		`json:"icon" bson:"icon"`
+	CreatedAt		time.Time	`json:"createdAt" bson:"createdAt"`
+	UpdatedAt		time.Time	`json:"updatedAt" bson:"updatedAt"`
+}
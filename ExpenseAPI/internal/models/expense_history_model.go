@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ExpenseHistory is a change of an expense. The snapshots hold the entry before
+// and after the change; a missing snapshot stands for an entry that did not
+// exist or was in the trash.
+type ExpenseHistory struct {
+	HistoryId	string		`json:"historyId" bson:"historyId"`
+	ExpenseId	string		`json:"expenseId" bson:"expenseId"`
+	UserId		string		`json:"userId" bson:"userId"`
+	ActorId		string		`json:"actorId,omitempty" bson:"actorId,omitempty"`
+	Action		string		`json:"action" bson:"action"`
+	Before		interface{}	`json:"before,omitempty" bson:"before,omitempty"`
+	After		interface{}	`json:"after,omitempty" bson:"after,omitempty"`
+	ChangedAt	time.Time	`json:"changedAt" bson:"changedAt"`
+}
@@ -1,9 +1,34 @@
 package models
 
+import "time"
+
 type Expense struct {
 	ExpenseId   string	`json:"expenseId" bson:"expenseId"`
 	UserId      string	`json:"userId" bson:"userId"`
 	Description	string	`json:"description" bson:"description"`
-	Amount		float32	`json:"amount" bson:"amount"`
+	Payee		string	`json:"payee" bson:"payee"`
+	Notes		string	`json:"notes" bson:"notes"`
+	Type		string	`json:"type" bson:"type"`
+	Amount		int64	`json:"amount" bson:"amount"`
+	Currency	string	`json:"currency" bson:"currency"`
 	Category	string	`json:"category" bson:"category"`
+	AccountId	string	`json:"accountId" bson:"accountId"`
+	ToAccountId	string	`json:"toAccountId" bson:"toAccountId"`
+	Tags		[]string	`json:"tags" bson:"tags"`
+	Date		time.Time	`json:"date" bson:"date"`
+	CreatedAt	time.Time	`json:"createdAt" bson:"createdAt"`
+	UpdatedAt	time.Time	`json:"updatedAt" bson:"updatedAt"`
+	RecurringId	string		`json:"recurringId,omitempty" bson:"recurringId,omitempty"`
+	ExternalId	string		`json:"externalId,omitempty" bson:"externalId,omitempty"`
+	Anomaly		*Anomaly	`json:"anomaly,omitempty" bson:"anomaly,omitempty"`
+	DeletedAt	*time.Time	`json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	Version		int64		`json:"version" bson:"version"`
+}
+
+type Anomaly struct {
+	Score			float64		`json:"score" bson:"score"`
+	Reason			string		`json:"reason" bson:"reason"`
+	FlaggedAt		time.Time	`json:"flaggedAt" bson:"flaggedAt"`
+	Acknowledged	bool		`json:"acknowledged" bson:"acknowledged"`
+	AcknowledgedAt	*time.Time	`json:"acknowledgedAt,omitempty" bson:"acknowledgedAt,omitempty"`
 }
\ No newline at end of file
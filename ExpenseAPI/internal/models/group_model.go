@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+type GroupMember struct {
+	UserId	string	`json:"userId" bson:"userId"`
+	Name	string	`json:"name" bson:"name"`
+}
+
+type Group struct {
+	GroupId		string			`json:"groupId" bson:"groupId"`
+	Name		string			`json:"name" bson:"name"`
+	OwnerId		string			`json:"ownerId" bson:"ownerId"`
+	Currency	string			`json:"currency" bson:"currency"`
+	Members		[]GroupMember	`json:"members" bson:"members"`
+	CreatedAt	time.Time		`json:"createdAt" bson:"createdAt"`
+	UpdatedAt	time.Time		`json:"updatedAt" bson:"updatedAt"`
+}
+
+type GroupSplit struct {
+	UserId	string	`json:"userId" bson:"userId"`
+	Shares	int64	`json:"shares,omitempty" bson:"shares,omitempty"`
+	Amount	int64	`json:"amount" bson:"amount"`
+}
+
+type GroupExpense struct {
+	GroupExpenseId	string			`json:"groupExpenseId" bson:"groupExpenseId"`
+	GroupId			string			`json:"groupId" bson:"groupId"`
+	Description		string			`json:"description" bson:"description"`
+	Amount			int64			`json:"amount" bson:"amount"`
+	Currency		string			`json:"currency" bson:"currency"`
+	PaidBy			string			`json:"paidBy" bson:"paidBy"`
+	SplitType		string			`json:"splitType" bson:"splitType"`
+	Splits			[]GroupSplit	`json:"splits" bson:"splits"`
+	Date			time.Time		`json:"date" bson:"date"`
+	CreatedBy		string			`json:"createdBy" bson:"createdBy"`
+	CreatedAt		time.Time		`json:"createdAt" bson:"createdAt"`
+}
+
+type Settlement struct {
+	SettlementId	string		`json:"settlementId" bson:"settlementId"`
+	GroupId			string		`json:"groupId" bson:"groupId"`
+	FromUserId		string		`json:"fromUserId" bson:"fromUserId"`
+	ToUserId		string		`json:"toUserId" bson:"toUserId"`
+	Amount			int64		`json:"amount" bson:"amount"`
+	Currency		string		`json:"currency" bson:"currency"`
+	Date			time.Time	`json:"date" bson:"date"`
+	CreatedBy		string		`json:"createdBy" bson:"createdBy"`
+	CreatedAt		time.Time	`json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type Receipt struct {
+	ReceiptId	string		`json:"receiptId" bson:"receiptId"`
+	ExpenseId	string		`json:"expenseId" bson:"expenseId"`
+	UserId		string		`json:"userId" bson:"userId"`
+	BlobId		string		`json:"-" bson:"blobId"`
+	FileName	string		`json:"fileName" bson:"fileName"`
+	ContentType	string		`json:"contentType" bson:"contentType"`
+	Size		int64		`json:"size" bson:"size"`
+	CreatedAt	time.Time	`json:"createdAt" bson:"createdAt"`
+}
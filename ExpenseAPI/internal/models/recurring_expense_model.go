@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type RecurringExpense struct {
+	RecurringId		string		`json:"recurringId" bson:"recurringId"`
+	UserId			string		`json:"userId" bson:"userId"`
+	Description		string		`json:"description" bson:"description"`
+	Type			string		`json:"type" bson:"type"`
+	Amount			int64		`json:"amount" bson:"amount"`
+	Currency		string		`json:"currency" bson:"currency"`
+	Category		string		`json:"category" bson:"category"`
+	Frequency		string		`json:"frequency" bson:"frequency"`
+	Interval		int			`json:"interval" bson:"interval"`
+	StartDate		time.Time	`json:"startDate" bson:"startDate"`
+	EndDate			*time.Time	`json:"endDate" bson:"endDate"`
+	LastOccurrence	*time.Time	`json:"lastOccurrence" bson:"lastOccurrence"`
+	NextOccurrence	*time.Time	`json:"nextOccurrence" bson:"nextOccurrence"`
+	CreatedAt		time.Time	`json:"createdAt" bson:"createdAt"`
+	UpdatedAt		time.Time	`json:"updatedAt" bson:"updatedAt"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Rule struct {
+	RuleId		string		`json:"ruleId" bson:"ruleId"`
+	UserId		string		`json:"userId" bson:"userId"`
+	Name		string		`json:"name" bson:"name"`
+	Priority	int			`json:"priority" bson:"priority"`
+	Field		string		`json:"field" bson:"field"`
+	MatchType	string		`json:"matchType" bson:"matchType"`
+	Pattern		string		`json:"pattern" bson:"pattern"`
+	Currency	string		`json:"currency" bson:"currency"`
+	MinAmount	*int64		`json:"minAmount" bson:"minAmount"`
+	MaxAmount	*int64		`json:"maxAmount" bson:"maxAmount"`
+	Category	string		`json:"category" bson:"category"`
+	Tags		[]string	`json:"tags" bson:"tags"`
+	CreatedAt	time.Time	`json:"createdAt" bson:"createdAt"`
+	UpdatedAt	time.Time	`json:"updatedAt" bson:"updatedAt"`
+}
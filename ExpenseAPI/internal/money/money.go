@@ -0,0 +1,133 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Amounts are kept as integer minor units (e.g. cents) of their currency.
+// The number of minor digits of each currency follows ISO 4217.
+var exponents = map[string]int{
+	"AED": 2, "AFN": 2, "ALL": 2, "AMD": 2, "ANG": 2, "AOA": 2, "ARS": 2, "AUD": 2, "AWG": 2, "AZN": 2,
+	"BAM": 2, "BBD": 2, "BDT": 2, "BGN": 2, "BHD": 3, "BIF": 0, "BMD": 2, "BND": 2, "BOB": 2, "BRL": 2,
+	"BSD": 2, "BTN": 2, "BWP": 2, "BYN": 2, "BZD": 2, "CAD": 2, "CDF": 2, "CHF": 2, "CLF": 4, "CLP": 0,
+	"CNY": 2, "COP": 2, "CRC": 2, "CUP": 2, "CVE": 2, "CZK": 2, "DJF": 0, "DKK": 2, "DOP": 2, "DZD": 2,
+	"EGP": 2, "ERN": 2, "ETB": 2, "EUR": 2, "FJD": 2, "FKP": 2, "GBP": 2, "GEL": 2, "GHS": 2, "GIP": 2,
+	"GMD": 2, "GNF": 0, "GTQ": 2, "GYD": 2, "HKD": 2, "HNL": 2, "HTG": 2, "HUF": 2, "IDR": 2, "ILS": 2,
+	"INR": 2, "IQD": 3, "IRR": 2, "ISK": 0, "JMD": 2, "JOD": 3, "JPY": 0, "KES": 2, "KGS": 2, "KHR": 2,
+	"KMF": 0, "KPW": 2, "KRW": 0, "KWD": 3, "KYD": 2, "KZT": 2, "LAK": 2, "LBP": 2, "LKR": 2, "LRD": 2,
+	"LSL": 2, "LYD": 3, "MAD": 2, "MDL": 2, "MGA": 2, "MKD": 2, "MMK": 2, "MNT": 2, "MOP": 2, "MRU": 2,
+	"MUR": 2, "MVR": 2, "MWK": 2, "MXN": 2, "MYR": 2, "MZN": 2, "NAD": 2, "NGN": 2, "NIO": 2, "NOK": 2,
+	"NPR": 2, "NZD": 2, "OMR": 3, "PAB": 2, "PEN": 2, "PGK": 2, "PHP": 2, "PKR": 2, "PLN": 2, "PYG": 0,
+	"QAR": 2, "RON": 2, "RSD": 2, "RUB": 2, "RWF": 0, "SAR": 2, "SBD": 2, "SCR": 2, "SDG": 2, "SEK": 2,
+	"SGD": 2, "SHP": 2, "SLE": 2, "SOS": 2, "SRD": 2, "SSP": 2, "STN": 2, "SVC": 2, "SYP": 2, "SZL": 2,
+	"THB": 2, "TJS": 2, "TMT": 2, "TND": 3, "TOP": 2, "TRY": 2, "TTD": 2, "TWD": 2, "TZS": 2, "UAH": 2,
+	"UGX": 0, "USD": 2, "UYI": 0, "UYU": 2, "UYW": 4, "UZS": 2, "VES": 2, "VND": 0, "VUV": 0, "WST": 2,
+	"XAF": 0, "XCD": 2, "XOF": 0, "XPF": 0, "YER": 2, "ZAR": 2, "ZMW": 2, "ZWG": 2,
+}
+
+func IsCurrency(code string) bool {
+	_, ok := exponents[code]
+	return ok
+}
+
+func Exponent(code string) (int, bool) {
+	exponent, ok := exponents[code]
+	return exponent, ok
+}
+
+func DefaultCurrency() string {
+	currency := strings.ToUpper(strings.TrimSpace(os.Getenv("DEFAULT_CURRENCY")))
+	if !IsCurrency(currency) {
+		return "USD"
+	}
+	return currency
+}
+
+// Parse converts a decimal string such as "12.5" or "-3.07" into minor units,
+// rejecting values with more fraction digits than the currency allows.
+func Parse(value string, currency string) (int64, error) {
+	exponent, ok := exponents[currency]
+	if !ok {
+		return 0, errors.New("Currency is unknown!")
+	}
+
+	value = strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" && fraction == "" {
+		return 0, errors.New("Amount is empty!")
+	}
+	if len(fraction) > exponent {
+		// Trailing zeros beyond the currency precision do not change the value.
+		trimmed := strings.TrimRight(fraction[exponent:], "0")
+		if trimmed != "" {
+			return 0, errors.New("Amount has more decimal places than the currency allows!")
+		}
+		fraction = fraction[:exponent]
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	digits := whole + fraction
+	for _, digit := range digits {
+		if digit < '0' || digit > '9' {
+			return 0, errors.New("Amount is not a decimal number!")
+		}
+	}
+	if digits == "" {
+		digits = "0"
+	}
+
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, errors.New("Amount is out of range!")
+	}
+	if negative {
+		minor = -minor
+	}
+	return minor, nil
+}
+
+// Format renders minor units as a decimal string with exactly the currency precision.
+func Format(minor int64, currency string) string {
+	exponent, ok := exponents[currency]
+	if !ok {
+		exponent = 2
+	}
+
+	sign := ""
+	magnitude := strconv.FormatInt(minor, 10)
+	if minor < 0 {
+		sign = "-"
+		magnitude = magnitude[1:]
+	}
+	if exponent == 0 {
+		return sign + magnitude
+	}
+	if len(magnitude) <= exponent {
+		magnitude = strings.Repeat("0", exponent-len(magnitude)+1) + magnitude
+	}
+	split := len(magnitude) - exponent
+	return sign + magnitude[:split] + "." + magnitude[split:]
+}
+
+// FromFloat rounds a legacy floating point amount to the nearest minor unit.
+func FromFloat(value float64, currency string) (int64, error) {
+	exponent, ok := exponents[currency]
+	if !ok {
+		return 0, errors.New("Currency is unknown!")
+	}
+	scaled := math.Round(value * math.Pow10(exponent))
+	if math.IsNaN(scaled) || math.Abs(scaled) > math.MaxInt64/2 {
+		return 0, errors.New("Amount is out of range!")
+	}
+	return int64(scaled), nil
+}
@@ -0,0 +1,109 @@
+package money
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{value: "12.5", currency: "USD", want: 1250},
+		{value: "-3.07", currency: "USD", want: -307},
+		{value: "+3.07", currency: "USD", want: 307},
+		{value: " 42 ", currency: "USD", want: 4200},
+		{value: ".5", currency: "USD", want: 50},
+		{value: "5.", currency: "USD", want: 500},
+		{value: "0", currency: "USD", want: 0},
+		{value: "1.500", currency: "USD", want: 150},
+		{value: "1.50000", currency: "USD", want: 150},
+		{value: "1.501", currency: "USD", wantErr: true},
+		{value: "100", currency: "JPY", want: 100},
+		{value: "100.0", currency: "JPY", want: 100},
+		{value: "100.5", currency: "JPY", wantErr: true},
+		{value: "1.234", currency: "BHD", want: 1234},
+		{value: "1.2340", currency: "BHD", want: 1234},
+		{value: "1.2345", currency: "BHD", wantErr: true},
+		{value: "0.0001", currency: "CLF", want: 1},
+		{value: "", currency: "USD", wantErr: true},
+		{value: "-", currency: "USD", wantErr: true},
+		{value: "1,50", currency: "USD", wantErr: true},
+		{value: "1e3", currency: "USD", wantErr: true},
+		{value: "1.2.3", currency: "USD", wantErr: true},
+		{value: "92233720368547758.08", currency: "USD", wantErr: true},
+		{value: "1", currency: "XYZ", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.value, test.currency)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q, %q) = %d, want an error", test.value, test.currency, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q, %q) returned %v", test.value, test.currency, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Parse(%q, %q) = %d, want %d", test.value, test.currency, got, test.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		minor    int64
+		currency string
+		want     string
+	}{
+		{minor: 1250, currency: "USD", want: "12.50"},
+		{minor: 0, currency: "USD", want: "0.00"},
+		{minor: 7, currency: "USD", want: "0.07"},
+		{minor: -7, currency: "USD", want: "-0.07"},
+		{minor: -307, currency: "USD", want: "-3.07"},
+		{minor: 100, currency: "JPY", want: "100"},
+		{minor: -100, currency: "JPY", want: "-100"},
+		{minor: 1, currency: "BHD", want: "0.001"},
+		{minor: 1234, currency: "BHD", want: "1.234"},
+		{minor: 1, currency: "CLF", want: "0.0001"},
+		{minor: 1250, currency: "XYZ", want: "12.50"},
+	}
+	for _, test := range tests {
+		if got := Format(test.minor, test.currency); got != test.want {
+			t.Errorf("Format(%d, %q) = %q, want %q", test.minor, test.currency, got, test.want)
+		}
+	}
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	for _, currency := range []string{"USD", "JPY", "BHD", "CLF"} {
+		for _, minor := range []int64{0, 1, -1, 99, 100, -12345, 1000000} {
+			got, err := Parse(Format(minor, currency), currency)
+			if err != nil || got != minor {
+				t.Errorf("Parse(Format(%d, %q)) = %d, %v", minor, currency, got, err)
+			}
+		}
+	}
+}
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		value    float64
+		currency string
+		want     int64
+	}{
+		{value: 0.1 + 0.2, currency: "USD", want: 30},
+		{value: 19.99, currency: "USD", want: 1999},
+		{value: -4.005, currency: "USD", want: -401},
+		{value: 1500.4, currency: "JPY", want: 1500},
+		{value: 1.2345, currency: "BHD", want: 1235},
+	}
+	for _, test := range tests {
+		got, err := FromFloat(test.value, test.currency)
+		if err != nil || got != test.want {
+			t.Errorf("FromFloat(%v, %q) = %d, %v, want %d", test.value, test.currency, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package rates
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"expense/internal/money"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func LoadFile(path string) ([]Rate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		return ParseXML(file)
+	case ".csv":
+		return ParseCSV(file)
+	default:
+		return nil, errors.New("Exchange rate file must be either XML or CSV!")
+	}
+}
+
+type ecbCube struct {
+	Time     string    `xml:"time,attr"`
+	Currency string    `xml:"currency,attr"`
+	Rate     string    `xml:"rate,attr"`
+	Cubes    []ecbCube `xml:"Cube"`
+}
+
+type ecbEnvelope struct {
+	Cube ecbCube `xml:"Cube"`
+}
+
+// ParseXML reads the ECB reference rate format (eurofxref-daily.xml and
+// eurofxref-hist.xml), where each dated Cube element holds one Cube per currency.
+func ParseXML(reader io.Reader) ([]Rate, error) {
+	envelope := ecbEnvelope{}
+	if err := xml.NewDecoder(reader).Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	result := make([]Rate, 0)
+	for _, day := range envelope.Cube.Cubes {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, err
+		}
+		for _, cube := range day.Cubes {
+			rate, ok := parseRate(cube.Currency, cube.Rate)
+			if !ok {
+				continue
+			}
+			rate.Date = date
+			result = append(result, rate)
+		}
+	}
+	return result, nil
+}
+
+// ParseCSV reads either the wide ECB layout ("Date,USD,JPY,...", one row per
+// day) or a long layout with "date,currency,rate" columns.
+func ParseCSV(reader io.Reader) ([]Rate, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	if len(header) == 0 || !strings.EqualFold(header[0], "date") {
+		return nil, errors.New("Exchange rate file must start with a \"Date\" column!")
+	}
+	long := len(header) == 3 && strings.EqualFold(header[1], "currency") && strings.EqualFold(header[2], "rate")
+
+	result := make([]Rate, 0)
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, err
+		}
+
+		if long {
+			if len(record) < 3 {
+				continue
+			}
+			if rate, ok := parseRate(record[1], record[2]); ok {
+				rate.Date = date
+				result = append(result, rate)
+			}
+			continue
+		}
+
+		for i := 1; i < len(record) && i < len(header); i++ {
+			if rate, ok := parseRate(header[i], record[i]); ok {
+				rate.Date = date
+				result = append(result, rate)
+			}
+		}
+	}
+	return result, nil
+}
+
+func parseRate(currency string, value string) (Rate, bool) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !money.IsCurrency(currency) {
+		return Rate{}, false
+	}
+	rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || rate.Sign() <= 0 {
+		// The ECB marks missing quotes with "N/A".
+		return Rate{}, false
+	}
+	return Rate{Currency: currency, Value: rate}, true
+}
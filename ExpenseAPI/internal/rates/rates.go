@@ -0,0 +1,149 @@
+package rates
+
+import (
+	"errors"
+	"expense/internal/money"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rates are quoted the way the ECB publishes them: units of a currency per
+// one unit of the reference currency (EUR).
+const ReferenceCurrency = "EUR"
+
+type Rate struct {
+	Date     time.Time
+	Currency string
+	Value    *big.Rat
+}
+
+type datedRate struct {
+	date  time.Time
+	value *big.Rat
+}
+
+type Table struct {
+	mutex sync.RWMutex
+	rates map[string][]datedRate
+}
+
+func NewTable() *Table {
+	return &Table{
+		rates: make(map[string][]datedRate),
+	}
+}
+
+func (t *Table) Add(newRates ...Rate) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	updates := make(map[string]map[time.Time]*big.Rat)
+	for _, rate := range newRates {
+		if updates[rate.Currency] == nil {
+			updates[rate.Currency] = make(map[time.Time]*big.Rat)
+		}
+		updates[rate.Currency][truncateToDay(rate.Date)] = rate.Value
+	}
+
+	for currency, values := range updates {
+		series := make([]datedRate, 0, len(t.rates[currency])+len(values))
+		for _, existing := range t.rates[currency] {
+			if _, replaced := values[existing.date]; !replaced {
+				series = append(series, existing)
+			}
+		}
+		for date, value := range values {
+			series = append(series, datedRate{date: date, value: value})
+		}
+		sort.Slice(series, func(i, j int) bool {
+			return series[i].date.Before(series[j].date)
+		})
+		t.rates[currency] = series
+	}
+}
+
+// Lookup returns the most recent rate published on or before the given date.
+func (t *Table) Lookup(currency string, date time.Time) (*big.Rat, error) {
+	if currency == ReferenceCurrency {
+		return big.NewRat(1, 1), nil
+	}
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	series := t.rates[currency]
+	date = truncateToDay(date)
+	index := sort.Search(len(series), func(i int) bool {
+		return series[i].date.After(date)
+	})
+	if index == 0 {
+		return nil, errors.New("No exchange rate is available for " + currency + " on " + date.Format("2006-01-02") + "!")
+	}
+	return series[index-1].value, nil
+}
+
+// Convert turns an amount in minor units of one currency into minor units of
+// another, using the rates valid on the given date. It also returns the
+// applied rate (units of "to" per unit of "from").
+func (t *Table) Convert(amount int64, from string, to string, date time.Time) (int64, *big.Rat, error) {
+	if from == to {
+		return amount, big.NewRat(1, 1), nil
+	}
+
+	fromExponent, ok := money.Exponent(from)
+	if !ok {
+		return 0, nil, errors.New("Currency is unknown!")
+	}
+	toExponent, ok := money.Exponent(to)
+	if !ok {
+		return 0, nil, errors.New("Currency is unknown!")
+	}
+
+	fromRate, err := t.Lookup(from, date)
+	if err != nil {
+		return 0, nil, err
+	}
+	toRate, err := t.Lookup(to, date)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rate := new(big.Rat).Quo(toRate, fromRate)
+	value := new(big.Rat).SetInt64(amount)
+	value.Mul(value, rate)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toExponent), pow10(fromExponent)))
+
+	return round(value), rate, nil
+}
+
+func round(value *big.Rat) int64 {
+	// Round half away from zero.
+	numerator := new(big.Int).Abs(value.Num())
+	denominator := value.Denom()
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if new(big.Int).Mul(remainder, big.NewInt(2)).Cmp(denominator) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if value.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+	return quotient.Int64()
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
+
+func truncateToDay(date time.Time) time.Time {
+	year, month, day := date.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func FormatRate(value *big.Rat) string {
+	formatted := value.FloatString(10)
+	formatted = strings.TrimRight(formatted, "0")
+	return strings.TrimSuffix(formatted, ".")
+}
@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrBlobNotFound = errors.New("blob is not found")
+
+type BlobStore interface {
+	Upload(ctx context.Context, name string, contentType string, data []byte) (string, error)
+	Download(ctx context.Context, blobId string) ([]byte, error)
+	Delete(ctx context.Context, blobId string) error
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GridFSBlobStore struct {
+	bucket	*gridfs.Bucket
+}
+
+func NewGridFSBlobStore(r *MongoDBRepository, bucketName string) (*GridFSBlobStore, error) {
+	bucket, err := gridfs.NewBucket(r.database, options.GridFSBucket().SetName(bucketName))
+	if err != nil {
+		return nil, err
+	}
+	return &GridFSBlobStore{
+		bucket: bucket,
+	}, nil
+}
+
+func (b *GridFSBlobStore) Upload(ctx context.Context, name string, contentType string, data []byte) (string, error) {
+	opts := options.GridFSUpload().SetMetadata(bson.M{"contentType": contentType})
+	blobId, err := b.bucket.UploadFromStream(name, bytes.NewReader(data), opts)
+	if err != nil {
+		return "", err
+	}
+	return blobId.Hex(), nil
+}
+
+func (b *GridFSBlobStore) Download(ctx context.Context, blobId string) ([]byte, error) {
+	objectId, err := primitive.ObjectIDFromHex(blobId)
+	if err != nil {
+		return nil, ErrBlobNotFound
+	}
+	buffer := bytes.Buffer{}
+	if _, err := b.bucket.DownloadToStream(objectId, &buffer); err != nil {
+		if err == gridfs.ErrFileNotFound {
+			return nil, ErrBlobNotFound
+		}
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (b *GridFSBlobStore) Delete(ctx context.Context, blobId string) error {
+	objectId, err := primitive.ObjectIDFromHex(blobId)
+	if err != nil {
+		return ErrBlobNotFound
+	}
+	if err := b.bucket.DeleteContext(ctx, objectId); err != nil {
+		if err == gridfs.ErrFileNotFound {
+			return ErrBlobNotFound
+		}
+		return err
+	}
+	return nil
+}
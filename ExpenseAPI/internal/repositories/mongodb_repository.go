@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"log"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -9,6 +10,12 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrNoMatch is returned by conditional updates when no document matched.
+var ErrNoMatch = errors.New("no document is matched")
+
+// ErrDuplicate is returned by writes that would break a unique index.
+var ErrDuplicate = errors.New("document already exists")
+
 type MongoDBRepository struct {
 	client     *mongo.Client
 	database   *mongo.Database
@@ -36,6 +43,14 @@ func NewMongoDBRepository(uri string, databaseName string, collectionName string
 	}, nil
 }
 
+func (r *MongoDBRepository) WithCollection(collectionName string) *MongoDBRepository {
+	return &MongoDBRepository{
+		client:     r.client,
+		database:   r.database,
+		collection: r.database.Collection(collectionName),
+	}
+}
+
 func (r *MongoDBRepository) Close(ctx context.Context) error {
 	if err := r.client.Disconnect(ctx); err != nil {
 		log.Fatalf("Failed to disconnect from database client: %v", err)
@@ -49,9 +64,12 @@ type GenericResponse struct {
 	Data	[]map[string]interface{}
 }
 
-func (r *MongoDBRepository) Find(ctx context.Context, filter interface{}) (*GenericResponse, error) {
-	opts := options.Find().SetProjection(bson.M{"_id": 0})
-	cursor, err := r.collection.Find(ctx, filter, opts)
+// Find returns the matching documents without their "_id". Options such as a
+// sort, a limit or a projection are applied on top; a projection replaces the
+// default one and should leave out "_id" as well.
+func (r *MongoDBRepository) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*GenericResponse, error) {
+	findOptions := options.MergeFindOptions(append([]*options.FindOptions{options.Find().SetProjection(bson.M{"_id": 0})}, opts...)...)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return &GenericResponse{
 			Success: false,
@@ -74,6 +92,9 @@ func (r *MongoDBRepository) Find(ctx context.Context, filter interface{}) (*Gene
 
 func (r *MongoDBRepository) Insert(ctx context.Context, document interface{}) (*GenericResponse, error) {
 	_, err := r.collection.InsertOne(ctx, document)
+	if mongo.IsDuplicateKeyError(err) {
+		err = ErrDuplicate
+	}
 	if err != nil {
 		return &GenericResponse{
 			Success: false,
@@ -88,6 +109,43 @@ func (r *MongoDBRepository) Insert(ctx context.Context, document interface{}) (*
 
 func (r *MongoDBRepository) Update(ctx context.Context, filter interface{}, update interface{}) (*GenericResponse, error) {
 	_, err := r.collection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		err = ErrDuplicate
+	}
+	if err != nil {
+		return &GenericResponse{
+			Success: false,
+			Data: nil,
+		}, err
+	}
+	return &GenericResponse{
+		Success: true,
+		Data: nil,
+	}, nil
+}
+
+// UpdateIfMatch updates the document matching the filter and returns
+// ErrNoMatch if there is none, e.g. because the filter asks for a version of
+// the document that was changed in the meantime.
+func (r *MongoDBRepository) UpdateIfMatch(ctx context.Context, filter interface{}, update interface{}) (*GenericResponse, error) {
+	updateResult, err := r.collection.UpdateOne(ctx, filter, update)
+	if err == nil && updateResult.MatchedCount == 0 {
+		err = ErrNoMatch
+	}
+	if err != nil {
+		return &GenericResponse{
+			Success: false,
+			Data: nil,
+		}, err
+	}
+	return &GenericResponse{
+		Success: true,
+		Data: nil,
+	}, nil
+}
+
+func (r *MongoDBRepository) UpdateMany(ctx context.Context, filter interface{}, update interface{}) (*GenericResponse, error) {
+	_, err := r.collection.UpdateMany(ctx, filter, update)
 	if err != nil {
 		return &GenericResponse{
 			Success: false,
@@ -113,3 +171,146 @@ func (r *MongoDBRepository) Delete(ctx context.Context, filter interface{}) (*Ge
 		Data: nil,
 	}, nil
 }
+
+func (r *MongoDBRepository) UpsertMany(ctx context.Context, filters []interface{}, updates []interface{}) (*GenericResponse, error) {
+	writeModels := make([]mongo.WriteModel, 0, len(filters))
+	for i := range filters {
+		writeModels = append(writeModels, mongo.NewUpdateOneModel().SetFilter(filters[i]).SetUpdate(updates[i]).SetUpsert(true))
+	}
+	if len(writeModels) == 0 {
+		return &GenericResponse{
+			Success: true,
+			Data: nil,
+		}, nil
+	}
+	_, err := r.collection.BulkWrite(ctx, writeModels, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return &GenericResponse{
+			Success: false,
+			Data: nil,
+		}, err
+	}
+	return &GenericResponse{
+		Success: true,
+		Data: nil,
+	}, nil
+}
+
+func (r *MongoDBRepository) CreateIndex(ctx context.Context, keys interface{}, opts ...*options.IndexOptions) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys, Options: options.MergeIndexOptions(opts...)})
+	return err
+}
+
+func (r *MongoDBRepository) DeleteMany(ctx context.Context, filter interface{}) (*GenericResponse, error) {
+	_, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return &GenericResponse{
+			Success: false,
+			Data: nil,
+		}, err
+	}
+	return &GenericResponse{
+		Success: true,
+		Data: nil,
+	}, nil
+}
+
+func (r *MongoDBRepository) InsertMany(ctx context.Context, documents []interface{}) (*GenericResponse, error) {
+	if len(documents) == 0 {
+		return &GenericResponse{
+			Success: true,
+			Data: nil,
+		}, nil
+	}
+	_, err := r.collection.InsertMany(ctx, documents)
+	if err != nil {
+		return &GenericResponse{
+			Success: false,
+			Data: nil,
+		}, err
+	}
+	return &GenericResponse{
+		Success: true,
+		Data: nil,
+	}, nil
+}
+
+// InsertNew inserts the documents that do not break a unique index and returns
+// the positions of the ones that do.
+func (r *MongoDBRepository) InsertNew(ctx context.Context, documents []interface{}) ([]int, error) {
+	duplicates := make([]int, 0)
+	if len(documents) == 0 {
+		return duplicates, nil
+	}
+	_, err := r.collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return duplicates, err
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if !mongo.IsDuplicateKeyError(writeErr) {
+			return duplicates, err
+		}
+		duplicates = append(duplicates, writeErr.Index)
+	}
+	return duplicates, nil
+}
+
+// FindEach passes the matching documents to fn one by one in the given sort
+// order, without loading the whole result set. It stops at the first error.
+func (r *MongoDBRepository) FindEach(ctx context.Context, filter interface{}, sort interface{}, fn func(map[string]interface{}) error) error {
+	opts := options.Find().SetProjection(bson.M{"_id": 0}).SetSort(sort)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var doc map[string]interface{}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (r *MongoDBRepository) Aggregate(ctx context.Context, pipeline interface{}) (*GenericResponse, error) {
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return &GenericResponse{
+			Success: false,
+			Data: nil,
+		}, err
+	}
+	defer cursor.Close(ctx)
+	result := make([]map[string]interface{}, 0)
+	for cursor.Next(ctx) {
+		var doc map[string]interface{}
+		if err := cursor.Decode(&doc); err != nil {
+			log.Println(err)
+		}
+		result = append(result, doc)
+	}
+	return &GenericResponse{
+		Success: true,
+		Data: result,
+	}, nil
+}
+
+// WithTransaction runs fn in a transaction, which needs a replica set. The
+// operations in fn take part in it through the context they are given; fn may
+// run more than once when the transaction is retried.
+func (r *MongoDBRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := r.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionContext)
+	})
+	return err
+}
@@ -0,0 +1,122 @@
+package rules
+
+import (
+	"errors"
+	"expense/internal/models"
+	"regexp"
+	"sort"
+)
+
+// Fields a rule can match its pattern against. "any" matches when either the
+// payee or the description matches.
+var Fields = map[string]bool{
+	"payee":       true,
+	"description": true,
+	"any":         true,
+}
+
+var MatchTypes = map[string]bool{
+	"contains": true,
+	"regex":    true,
+}
+
+type Entry struct {
+	Payee       string
+	Description string
+	Amount      int64
+	Currency    string
+}
+
+type Matcher struct {
+	rule    models.Rule
+	pattern *regexp.Regexp
+}
+
+// Compile prepares a rule for matching. Patterns are matched
+// case-insensitively; a "contains" pattern is matched literally.
+func Compile(rule models.Rule) (*Matcher, error) {
+	if !Fields[rule.Field] {
+		return nil, errors.New("Field is unknown!")
+	}
+	if !MatchTypes[rule.MatchType] {
+		return nil, errors.New("Match type is unknown!")
+	}
+
+	matcher := &Matcher{rule: rule}
+	if rule.Pattern != "" {
+		expression := rule.Pattern
+		if rule.MatchType == "contains" {
+			expression = regexp.QuoteMeta(expression)
+		}
+		pattern, err := regexp.Compile("(?i)" + expression)
+		if err != nil {
+			return nil, err
+		}
+		matcher.pattern = pattern
+	}
+	return matcher, nil
+}
+
+func (m *Matcher) Rule() models.Rule {
+	return m.rule
+}
+
+func (m *Matcher) Match(entry Entry) bool {
+	if m.pattern != nil {
+		matched := false
+		if m.rule.Field == "payee" || m.rule.Field == "any" {
+			matched = m.pattern.MatchString(entry.Payee)
+		}
+		if !matched && (m.rule.Field == "description" || m.rule.Field == "any") {
+			matched = m.pattern.MatchString(entry.Description)
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	// Amount bounds are inclusive and only apply to entries in the currency of the rule.
+	if m.rule.MinAmount != nil || m.rule.MaxAmount != nil {
+		if entry.Currency != m.rule.Currency {
+			return false
+		}
+		if m.rule.MinAmount != nil && entry.Amount < *m.rule.MinAmount {
+			return false
+		}
+		if m.rule.MaxAmount != nil && entry.Amount > *m.rule.MaxAmount {
+			return false
+		}
+	}
+	return true
+}
+
+// CompileAll compiles the rules in the order they are evaluated: by ascending
+// priority, then by creation. Rules that no longer compile are skipped.
+func CompileAll(rules []models.Rule) []*Matcher {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority < rules[j].Priority
+		}
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+
+	matchers := make([]*Matcher, 0, len(rules))
+	for _, rule := range rules {
+		matcher, err := Compile(rule)
+		if err != nil {
+			continue
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers
+}
+
+// First returns the first rule matching the entry.
+func First(matchers []*Matcher, entry Entry) (*Matcher, bool) {
+	for _, matcher := range matchers {
+		if matcher.Match(entry) {
+			return matcher, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,774 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense/internal/models"
+	"expense/internal/money"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errUnknownAccount = errors.New("account is unknown")
+
+func (s *ExpenseService) findAccount(ctx context.Context, userId string, accountId string) (*models.Account, error) {
+	filter := map[string]interface{}{
+		"userId":    userId,
+		"accountId": accountId,
+	}
+	result, err := s.accountRepo.Find(ctx, filter)
+	if !result.Success {
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		return nil, errUnknownAccount
+	}
+	account := models.Account{}
+	if err := decodeDocument(result.Data[0], &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// validateEntryAccounts checks that the accounts of an entry belong to the user.
+func (s *ExpenseService) validateEntryAccounts(ctx context.Context, userId string, accountIds ...string) error {
+	for _, accountId := range accountIds {
+		if accountId == "" {
+			continue
+		}
+		if _, err := s.findAccount(ctx, userId, accountId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func accountEntriesFilter(userId string, accountId string) map[string]interface{} {
+	return map[string]interface{}{
+		"userId": userId,
+		"$or": []interface{}{
+			map[string]interface{}{"accountId": accountId},
+			map[string]interface{}{"toAccountId": accountId},
+		},
+	}
+}
+
+type accountEntry struct {
+	ExpenseId		string		`json:"expenseId"`
+	Description		string		`json:"description"`
+	Type			string		`json:"type"`
+	Date			time.Time	`json:"date"`
+	Amount			string		`json:"amount"`
+	RunningBalance	string		`json:"runningBalance"`
+}
+
+// accountBalance computes the balance of an account at the end of the range
+// from its opening balance and the entries booked on it. Entries in another
+// currency are converted at the rate of their date. The running balance after
+// each entry within the range is returned as well.
+func (s *ExpenseService) accountBalance(ctx context.Context, account models.Account, from *time.Time, to *time.Time) (int64, []accountEntry, bool, error) {
+	filter := accountEntriesFilter(account.UserId, account.AccountId)
+	filter["deletedAt"] = notDeleted()
+	if to != nil {
+		filter["date"] = map[string]interface{}{"$lt": *to}
+	}
+	result, err := s.mongoDBRepo.Find(ctx, filter)
+	if !result.Success {
+		return 0, nil, false, err
+	}
+
+	documents := result.Data
+	sort.SliceStable(documents, func(i, j int) bool {
+		return expenseDate(documents[i]).Before(expenseDate(documents[j]))
+	})
+
+	balance := account.OpeningBalance
+	entries := make([]accountEntry, 0)
+	complete := true
+	for _, document := range documents {
+		amount, currency := documentAmount(document)
+		date := expenseDate(document)
+		converted, _, err := s.rates.Convert(amount, currency, account.Currency, date)
+		if err != nil {
+			complete = false
+			continue
+		}
+
+		// Money leaves the account for expenses and outgoing transfers.
+		entryType := entryType(document)
+		toAccountId, _ := document["toAccountId"].(string)
+		if entryType == "transfer" && toAccountId == account.AccountId {
+			balance += converted
+		} else if entryType == "income" {
+			balance += converted
+		} else {
+			converted = -converted
+			balance += converted
+		}
+
+		if from != nil && date.Before(*from) {
+			continue
+		}
+		expenseId, _ := document["expenseId"].(string)
+		description, _ := document["description"].(string)
+		entries = append(entries, accountEntry{
+			ExpenseId:      expenseId,
+			Description:    description,
+			Type:           entryType,
+			Date:           date,
+			Amount:         money.Format(converted, account.Currency),
+			RunningBalance: money.Format(balance, account.Currency),
+		})
+	}
+	return balance, entries, complete, nil
+}
+
+func formatAccount(account map[string]interface{}) {
+	currency, _ := account["currency"].(string)
+	switch openingBalance := account["openingBalance"].(type) {
+	case int64:
+		account["openingBalance"] = money.Format(openingBalance, currency)
+	case int32:
+		account["openingBalance"] = money.Format(int64(openingBalance), currency)
+	}
+}
+
+type getAccountServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	AccountId	string	`json:"accountId"`
+}
+
+type getAccountServiceResponse struct {
+	Message		string						`json:"message"`
+	Success		bool						`json:"success"`
+	Accounts	[]map[string]interface{}	`json:"accounts"`
+}
+
+func (s *ExpenseService) HandleGetAccount(data []byte, replyTo string, correlationId string) {
+	getAccountServiceRequestData := getAccountServiceRequest{}
+	err := json.Unmarshal(data, &getAccountServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetAccountResponse",
+			getAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": getAccountServiceRequestData.UserId,
+	}
+	if getAccountServiceRequestData.AccountId != "" {
+		filter["accountId"] = getAccountServiceRequestData.AccountId
+	}
+	result, err := s.accountRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetAccountResponse",
+			getAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	accounts := result.Data
+	for _, account := range accounts {
+		formatAccount(account)
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetAccountResponse",
+		getAccountServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Accounts: accounts,
+		},
+	)
+}
+
+type addAccountServiceRequest struct {
+	Action			string		`json:"action"`
+	UserId			string		`json:"userId"`
+	Name			string		`json:"name"`
+	Type			string		`json:"type"`
+	Currency		string		`json:"currency"`
+	OpeningBalance	json.Number	`json:"openingBalance"`
+}
+
+type addAccountServiceResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	Status		int		`json:"status,omitempty"`
+	AccountId	string	`json:"accountId"`
+}
+
+func (s *ExpenseService) HandleAddAccount(data []byte, replyTo string, correlationId string) {
+	addAccountServiceRequestData := addAccountServiceRequest{}
+	err := json.Unmarshal(data, &addAccountServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddAccountResponse",
+			addAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	openingBalance, err := money.Parse(addAccountServiceRequestData.OpeningBalance.String(), addAccountServiceRequestData.Currency)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddAccountResponse",
+			addAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	result, err := s.accountRepo.Find(context.Background(), map[string]interface{}{"userId": addAccountServiceRequestData.UserId})
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddAccountResponse",
+			addAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	for _, account := range result.Data {
+		if name, _ := account["name"].(string); strings.EqualFold(name, addAccountServiceRequestData.Name) {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"AddAccountResponse",
+				addAccountServiceResponse{
+					Message: "An account with this name already exists!",
+					Success: false,
+					Status: http.StatusConflict,
+				},
+			)
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	account := models.Account{
+		AccountId:      uuid.New().String(),
+		UserId:         addAccountServiceRequestData.UserId,
+		Name:           addAccountServiceRequestData.Name,
+		Type:           addAccountServiceRequestData.Type,
+		Currency:       addAccountServiceRequestData.Currency,
+		OpeningBalance: openingBalance,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	result, err = s.accountRepo.Insert(context.Background(), account)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddAccountResponse",
+			addAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AddAccountResponse",
+		addAccountServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			AccountId: account.AccountId,
+		},
+	)
+}
+
+type updateAccountServiceRequest struct {
+	Action			string		`json:"action"`
+	UserId			string		`json:"userId"`
+	AccountId		string		`json:"accountId"`
+	Name			string		`json:"name"`
+	Type			string		`json:"type"`
+	Currency		string		`json:"currency"`
+	OpeningBalance	json.Number	`json:"openingBalance"`
+}
+
+type updateAccountServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleUpdateAccount(data []byte, replyTo string, correlationId string) {
+	updateAccountServiceRequestData := updateAccountServiceRequest{}
+	err := json.Unmarshal(data, &updateAccountServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateAccountResponse",
+			updateAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	account, err := s.findAccount(context.Background(), updateAccountServiceRequestData.UserId, updateAccountServiceRequestData.AccountId)
+	if err == errUnknownAccount {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateAccountResponse",
+			updateAccountServiceResponse{
+				Message: "Account not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateAccountResponse",
+			updateAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	openingBalance, err := money.Parse(updateAccountServiceRequestData.OpeningBalance.String(), updateAccountServiceRequestData.Currency)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateAccountResponse",
+			updateAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	result, err := s.accountRepo.Find(context.Background(), map[string]interface{}{"userId": updateAccountServiceRequestData.UserId})
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateAccountResponse",
+			updateAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	for _, other := range result.Data {
+		otherId, _ := other["accountId"].(string)
+		if name, _ := other["name"].(string); otherId != account.AccountId && strings.EqualFold(name, updateAccountServiceRequestData.Name) {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"UpdateAccountResponse",
+				updateAccountServiceResponse{
+					Message: "An account with this name already exists!",
+					Success: false,
+					Status: http.StatusConflict,
+				},
+			)
+			return
+		}
+	}
+
+	// Changing the currency would reinterpret the opening balance, so it is only
+	// allowed while nothing is booked on the account.
+	if updateAccountServiceRequestData.Currency != account.Currency {
+		result, err := s.mongoDBRepo.Find(context.Background(), accountEntriesFilter(account.UserId, account.AccountId))
+		if !result.Success {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"UpdateAccountResponse",
+				updateAccountServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+		if len(result.Data) != 0 {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"UpdateAccountResponse",
+				updateAccountServiceResponse{
+					Message: "The currency of an account with entries cannot be changed!",
+					Success: false,
+					Status: http.StatusConflict,
+				},
+			)
+			return
+		}
+	}
+
+	filter := map[string]interface{}{
+		"userId":    account.UserId,
+		"accountId": account.AccountId,
+	}
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{
+			"name":           updateAccountServiceRequestData.Name,
+			"type":           updateAccountServiceRequestData.Type,
+			"currency":       updateAccountServiceRequestData.Currency,
+			"openingBalance": openingBalance,
+			"updatedAt":      time.Now().UTC(),
+		},
+	}
+	result, err = s.accountRepo.Update(context.Background(), filter, update)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateAccountResponse",
+			updateAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"UpdateAccountResponse",
+		updateAccountServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type removeAccountServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	AccountId	string	`json:"accountId"`
+}
+
+type removeAccountServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleRemoveAccount(data []byte, replyTo string, correlationId string) {
+	removeAccountServiceRequestData := removeAccountServiceRequest{}
+	err := json.Unmarshal(data, &removeAccountServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveAccountResponse",
+			removeAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	account, err := s.findAccount(context.Background(), removeAccountServiceRequestData.UserId, removeAccountServiceRequestData.AccountId)
+	if err == errUnknownAccount {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveAccountResponse",
+			removeAccountServiceResponse{
+				Message: "Account not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveAccountResponse",
+			removeAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	result, err := s.mongoDBRepo.Find(context.Background(), accountEntriesFilter(account.UserId, account.AccountId))
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveAccountResponse",
+			removeAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) != 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveAccountResponse",
+			removeAccountServiceResponse{
+				Message: "Account is in use!",
+				Success: false,
+				Status: http.StatusConflict,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":    account.UserId,
+		"accountId": account.AccountId,
+	}
+	result, err = s.accountRepo.Delete(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveAccountResponse",
+			removeAccountServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RemoveAccountResponse",
+		removeAccountServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type getAccountBalanceServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	AccountId	string		`json:"accountId"`
+	From		*time.Time	`json:"from"`
+	To			*time.Time	`json:"to"`
+}
+
+type accountBalanceServiceResponse struct {
+	AccountId		string			`json:"accountId"`
+	Name			string			`json:"name"`
+	Type			string			`json:"type"`
+	Currency		string			`json:"currency"`
+	OpeningBalance	string			`json:"openingBalance"`
+	Balance			string			`json:"balance"`
+	MissingRates	bool			`json:"missingRates"`
+	Entries			[]accountEntry	`json:"entries,omitempty"`
+}
+
+type getAccountBalanceServiceResponse struct {
+	Message		string							`json:"message"`
+	Success		bool							`json:"success"`
+	Status		int								`json:"status,omitempty"`
+	Accounts	[]accountBalanceServiceResponse	`json:"accounts"`
+}
+
+func (s *ExpenseService) HandleGetAccountBalance(data []byte, replyTo string, correlationId string) {
+	getAccountBalanceServiceRequestData := getAccountBalanceServiceRequest{}
+	err := json.Unmarshal(data, &getAccountBalanceServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetAccountBalanceResponse",
+			getAccountBalanceServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": getAccountBalanceServiceRequestData.UserId,
+	}
+	if getAccountBalanceServiceRequestData.AccountId != "" {
+		filter["accountId"] = getAccountBalanceServiceRequestData.AccountId
+	}
+	result, err := s.accountRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetAccountBalanceResponse",
+			getAccountBalanceServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if getAccountBalanceServiceRequestData.AccountId != "" && len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetAccountBalanceResponse",
+			getAccountBalanceServiceResponse{
+				Message: "Account not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	accounts := make([]accountBalanceServiceResponse, 0, len(result.Data))
+	for _, document := range result.Data {
+		account := models.Account{}
+		if err := decodeDocument(document, &account); err != nil {
+			log.Println(err)
+			continue
+		}
+		balance, entries, complete, err := s.accountBalance(context.Background(), account, getAccountBalanceServiceRequestData.From, getAccountBalanceServiceRequestData.To)
+		if err != nil {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"GetAccountBalanceResponse",
+				getAccountBalanceServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+
+		accountBalance := accountBalanceServiceResponse{
+			AccountId:      account.AccountId,
+			Name:           account.Name,
+			Type:           account.Type,
+			Currency:       account.Currency,
+			OpeningBalance: money.Format(account.OpeningBalance, account.Currency),
+			Balance:        money.Format(balance, account.Currency),
+			MissingRates:   !complete,
+		}
+		// The entries with their running balance are only listed for a single account.
+		if getAccountBalanceServiceRequestData.AccountId != "" {
+			accountBalance.Entries = entries
+		}
+		accounts = append(accounts, accountBalance)
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetAccountBalanceResponse",
+		getAccountBalanceServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Accounts: accounts,
+		},
+	)
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/anomaly"
+	"expense/internal/models"
+	"expense/internal/money"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// detectAnomaly scores a new expense against the user's expenses of the same
+// category in the year before it. Prior expenses in other currencies are
+// converted into the currency of the new one; those without a rate are left out.
+func (s *ExpenseService) detectAnomaly(ctx context.Context, userId string, category string, amount int64, currency string, date time.Time) (*models.Anomaly, error) {
+	filter := map[string]interface{}{
+		"userId":    userId,
+		"category":  category,
+		"type":      expenseTypeFilter(),
+		"deletedAt": notDeleted(),
+		"date": map[string]interface{}{
+			"$gte": date.AddDate(-1, 0, 0),
+			"$lt":  date,
+		},
+	}
+	result, err := s.mongoDBRepo.Find(ctx, filter)
+	if !result.Success {
+		return nil, err
+	}
+
+	history := make([]int64, 0, len(result.Data))
+	for _, expense := range result.Data {
+		expenseAmount, expenseCurrency := documentAmount(expense)
+		converted, _, err := s.rates.Convert(expenseAmount, expenseCurrency, currency, expenseDate(expense))
+		if err != nil {
+			continue
+		}
+		history = append(history, converted)
+	}
+
+	check := anomaly.Check(history, amount)
+	if !check.Flagged {
+		return nil, nil
+	}
+	usual := money.Format(check.Median, currency) + " " + currency
+	reason := fmt.Sprintf("Amount is far above the usual %s in \"%s\"!", usual, category)
+	if check.ExtraZero {
+		reason = fmt.Sprintf("Amount is about ten times the usual %s in \"%s\", check for an extra zero!", usual, category)
+	} else if check.Score < 0 {
+		reason = fmt.Sprintf("Amount is far below the usual %s in \"%s\"!", usual, category)
+	}
+	return &models.Anomaly{
+		Score:     check.Score,
+		Reason:    reason,
+		FlaggedAt: time.Now().UTC(),
+	}, nil
+}
+
+type getAnomalyServiceRequest struct {
+	Action			string	`json:"action"`
+	UserId			string	`json:"userId"`
+	Acknowledged	bool	`json:"acknowledged"`
+	BaseCurrency	string	`json:"baseCurrency"`
+}
+
+type getAnomalyServiceResponse struct {
+	Message		string						`json:"message"`
+	Success		bool						`json:"success"`
+	Expenses	[]map[string]interface{}	`json:"expenses"`
+}
+
+func (s *ExpenseService) HandleGetAnomaly(data []byte, replyTo string, correlationId string) {
+	getAnomalyServiceRequestData := getAnomalyServiceRequest{}
+	err := json.Unmarshal(data, &getAnomalyServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetAnomalyResponse",
+			getAnomalyServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":               getAnomalyServiceRequestData.UserId,
+		"anomaly":              map[string]interface{}{"$exists": true},
+		"anomaly.acknowledged": getAnomalyServiceRequestData.Acknowledged,
+		"deletedAt":            notDeleted(),
+	}
+	result, err := s.mongoDBRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetAnomalyResponse",
+			getAnomalyServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	baseCurrency := getAnomalyServiceRequestData.BaseCurrency
+	if !money.IsCurrency(baseCurrency) {
+		baseCurrency = money.DefaultCurrency()
+	}
+	expenses := result.Data
+	if expenses == nil {
+		expenses = []map[string]interface{}{}
+	}
+	s.convertExpenses(expenses, baseCurrency)
+	for _, expense := range expenses {
+		formatAmount(expense, baseCurrency)
+	}
+	sort.SliceStable(expenses, func(i, j int) bool {
+		return expenseDate(expenses[i]).After(expenseDate(expenses[j]))
+	})
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetAnomalyResponse",
+		getAnomalyServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Expenses: expenses,
+		},
+	)
+}
+
+type acknowledgeAnomalyServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+}
+
+type acknowledgeAnomalyServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleAcknowledgeAnomaly(data []byte, replyTo string, correlationId string) {
+	acknowledgeAnomalyServiceRequestData := acknowledgeAnomalyServiceRequest{}
+	err := json.Unmarshal(data, &acknowledgeAnomalyServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AcknowledgeAnomalyResponse",
+			acknowledgeAnomalyServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":    acknowledgeAnomalyServiceRequestData.UserId,
+		"expenseId": acknowledgeAnomalyServiceRequestData.ExpenseId,
+		"anomaly":   map[string]interface{}{"$exists": true},
+		"deletedAt": notDeleted(),
+	}
+	result, err := s.mongoDBRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AcknowledgeAnomalyResponse",
+			acknowledgeAnomalyServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AcknowledgeAnomalyResponse",
+			acknowledgeAnomalyServiceResponse{
+				Message: "Anomaly not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{
+			"anomaly.acknowledged":   true,
+			"anomaly.acknowledgedAt": time.Now().UTC(),
+		},
+		"$inc": map[string]interface{}{"version": 1},
+	}
+	result, err = s.mongoDBRepo.Update(context.Background(), filter, update)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AcknowledgeAnomalyResponse",
+			acknowledgeAnomalyServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AcknowledgeAnomalyResponse",
+		acknowledgeAnomalyServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
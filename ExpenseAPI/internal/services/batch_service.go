@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/models"
+	"log"
+	"net/http"
+)
+
+type batchOperationServiceRequest struct {
+	Index		int								`json:"index"`
+	Op			string							`json:"op"`
+	Create		*addExpenseServiceRequest		`json:"create"`
+	Update		*updateExpenseServiceRequest	`json:"update"`
+	ExpenseId	string							`json:"expenseId"`
+	Version		*int64							`json:"version,omitempty"`
+}
+
+type batchResultServiceResponse struct {
+	Index		int				`json:"index"`
+	Op			string			`json:"op"`
+	ExpenseId	string			`json:"expenseId,omitempty"`
+	Success		bool			`json:"success"`
+	Status		int				`json:"status"`
+	Message		string			`json:"message"`
+	Anomaly		*models.Anomaly	`json:"anomaly,omitempty"`
+	Version		int64			`json:"version,omitempty"`
+}
+
+type batchExpenseServiceRequest struct {
+	Action		string							`json:"action"`
+	UserId		string							`json:"userId"`
+	Atomic		bool							`json:"atomic"`
+	Operations	[]batchOperationServiceRequest	`json:"operations"`
+}
+
+type batchExpenseServiceResponse struct {
+	Message	string							`json:"message"`
+	Success	bool							`json:"success"`
+	Status	int								`json:"status,omitempty"`
+	Results	[]batchResultServiceResponse	`json:"results"`
+}
+
+// applyBatchOperation runs a single operation of a batch. Failures the client
+// can fix are reported in the result and returned as well, so that an atomic
+// batch can stop at them.
+func (s *ExpenseService) applyBatchOperation(ctx context.Context, userId string, operation batchOperationServiceRequest) (batchResultServiceResponse, error) {
+	result := batchResultServiceResponse{
+		Index:     operation.Index,
+		Op:        operation.Op,
+		ExpenseId: operation.ExpenseId,
+	}
+
+	var err error
+	status := http.StatusOK
+	switch {
+	case operation.Op == "create" && operation.Create != nil:
+		operation.Create.UserId = userId
+		var expense models.Expense
+		expense, err = s.addExpense(ctx, *operation.Create)
+		result.ExpenseId = expense.ExpenseId
+		result.Anomaly = expense.Anomaly
+		result.Version = expense.Version
+		status = http.StatusCreated
+	case operation.Op == "update" && operation.Update != nil:
+		operation.Update.UserId = userId
+		result.ExpenseId = operation.Update.ExpenseId
+		result.Version, err = s.updateExpense(ctx, *operation.Update)
+	case operation.Op == "delete":
+		err = s.removeExpense(ctx, userId, operation.ExpenseId, operation.Version)
+	default:
+		err = &entryError{status: http.StatusBadRequest, message: "Operation is invalid!"}
+	}
+
+	if entryErr, ok := err.(*entryError); ok {
+		result.Status = entryErr.status
+		result.Message = entryErr.message
+		return result, err
+	}
+	if err != nil {
+		result.Status = http.StatusInternalServerError
+		result.Message = "An error occured!"
+		return result, err
+	}
+	result.Success = true
+	result.Status = status
+	result.Message = "Operation is successful!"
+	return result, nil
+}
+
+func (s *ExpenseService) HandleBatchExpense(data []byte, replyTo string, correlationId string) {
+	batchExpenseServiceRequestData := batchExpenseServiceRequest{}
+	err := json.Unmarshal(data, &batchExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"BatchExpenseResponse",
+			batchExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	userId := batchExpenseServiceRequestData.UserId
+	results := make([]batchResultServiceResponse, 0, len(batchExpenseServiceRequestData.Operations))
+
+	if !batchExpenseServiceRequestData.Atomic {
+		for _, operation := range batchExpenseServiceRequestData.Operations {
+			result, err := s.applyBatchOperation(context.Background(), userId, operation)
+			if _, ok := err.(*entryError); !ok && err != nil {
+				log.Println(err)
+			}
+			results = append(results, result)
+		}
+	} else {
+		var failed *batchResultServiceResponse
+		err = s.mongoDBRepo.WithTransaction(context.Background(), func(ctx context.Context) error {
+			// The transaction may be retried, so each attempt starts over.
+			results = results[:0]
+			failed = nil
+			for _, operation := range batchExpenseServiceRequestData.Operations {
+				result, err := s.applyBatchOperation(ctx, userId, operation)
+				results = append(results, result)
+				if err != nil {
+					failed = &result
+					return err
+				}
+			}
+			return nil
+		})
+		if _, ok := err.(*entryError); ok && failed != nil {
+			// Nothing was written, so every other operation is reported as not applied.
+			notApplied := make([]batchResultServiceResponse, 0, len(batchExpenseServiceRequestData.Operations))
+			for _, operation := range batchExpenseServiceRequestData.Operations {
+				if operation.Index == failed.Index {
+					notApplied = append(notApplied, *failed)
+					continue
+				}
+				notApplied = append(notApplied, batchResultServiceResponse{
+					Index:   operation.Index,
+					Op:      operation.Op,
+					Status:  http.StatusFailedDependency,
+					Message: "Operation is not applied!",
+				})
+			}
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"BatchExpenseResponse",
+				batchExpenseServiceResponse{
+					Message: "Batch is rolled back, no changes were made!",
+					Success: false,
+					Status: failed.Status,
+					Results: notApplied,
+				},
+			)
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"BatchExpenseResponse",
+				batchExpenseServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"BatchExpenseResponse",
+		batchExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Results: results,
+		},
+	)
+}
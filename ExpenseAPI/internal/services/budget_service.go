@@ -0,0 +1,683 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/models"
+	"expense/internal/money"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// budgetPeriod returns the bounds [start, end) of the weekly (Monday based) or
+// monthly period that contains the given date.
+func budgetPeriod(period string, date time.Time) (time.Time, time.Time) {
+	year, month, day := date.UTC().Date()
+	if period == "weekly" {
+		offset := (int(date.UTC().Weekday()) + 6) % 7
+		start := time.Date(year, month, day-offset, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 7)
+	}
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+func (s *ExpenseService) budgetStatus(ctx context.Context, budget models.Budget, date time.Time) (map[string]interface{}, error) {
+	start, end := budgetPeriod(budget.Period, date)
+	// A budget on a parent category also covers the expenses of its subcategories.
+	categories, err := s.categoryWithDescendants(ctx, budget.UserId, budget.Category)
+	if err != nil {
+		return nil, err
+	}
+	filter := map[string]interface{}{
+		"userId":    budget.UserId,
+		"category":  map[string]interface{}{"$in": categories},
+		"type":      expenseTypeFilter(),
+		"deletedAt": notDeleted(),
+		"date": map[string]interface{}{
+			"$gte": start,
+			"$lt":  end,
+		},
+	}
+	result, err := s.mongoDBRepo.Find(ctx, filter)
+	if !result.Success {
+		return nil, err
+	}
+
+	spent := int64(0)
+	complete := true
+	for _, expense := range result.Data {
+		amount, currency := documentAmount(expense)
+		converted, _, err := s.rates.Convert(amount, currency, budget.Currency, expenseDate(expense))
+		if err != nil {
+			complete = false
+			continue
+		}
+		spent += converted
+	}
+
+	percentUsed := 0.0
+	if budget.Amount != 0 {
+		percentUsed = math.Round(float64(spent)/float64(budget.Amount)*1000) / 10
+	}
+
+	return map[string]interface{}{
+		"budgetId":     budget.BudgetId,
+		"category":     budget.Category,
+		"period":       budget.Period,
+		"periodStart":  start,
+		"periodEnd":    end,
+		"currency":     budget.Currency,
+		"amount":       money.Format(budget.Amount, budget.Currency),
+		"spent":        money.Format(spent, budget.Currency),
+		"remaining":    money.Format(budget.Amount-spent, budget.Currency),
+		"percentUsed":  percentUsed,
+		"overBudget":   spent > budget.Amount,
+		"missingRates": !complete,
+	}, nil
+}
+
+type getBudgetServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	BudgetId	string	`json:"budgetId"`
+}
+
+type getBudgetServiceResponse struct {
+	Message	string						`json:"message"`
+	Success	bool						`json:"success"`
+	Budgets	[]map[string]interface{}	`json:"budgets"`
+}
+
+func (s *ExpenseService) HandleGetBudget(data []byte, replyTo string, correlationId string) {
+	getBudgetServiceRequestData := getBudgetServiceRequest{}
+	err := json.Unmarshal(data, &getBudgetServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetBudgetResponse",
+			getBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": getBudgetServiceRequestData.UserId,
+	}
+	if getBudgetServiceRequestData.BudgetId != "" {
+		filter["budgetId"] = getBudgetServiceRequestData.BudgetId
+	}
+	result, err := s.budgetRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetBudgetResponse",
+			getBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	budgets := result.Data
+	for _, budget := range budgets {
+		formatAmount(budget, "")
+	}
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetBudgetResponse",
+		getBudgetServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Budgets: budgets,
+		},
+	)
+}
+
+type addBudgetServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	Category	string		`json:"category"`
+	Amount		json.Number	`json:"amount"`
+	Currency	string		`json:"currency"`
+	Period		string		`json:"period"`
+}
+
+type addBudgetServiceResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	Status		int		`json:"status,omitempty"`
+	BudgetId	string	`json:"budgetId"`
+}
+
+func (s *ExpenseService) HandleAddBudget(data []byte, replyTo string, correlationId string) {
+	addBudgetServiceRequestData := addBudgetServiceRequest{}
+	err := json.Unmarshal(data, &addBudgetServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddBudgetResponse",
+			addBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	amount, err := money.Parse(addBudgetServiceRequestData.Amount.String(), addBudgetServiceRequestData.Currency)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddBudgetResponse",
+			addBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	category, err := s.resolveCategory(context.Background(), addBudgetServiceRequestData.UserId, addBudgetServiceRequestData.Category)
+	if err == errUnknownCategory {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddBudgetResponse",
+			addBudgetServiceResponse{
+				Message: "Category is unknown!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddBudgetResponse",
+			addBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	addBudgetServiceRequestData.Category = category
+
+	filter := map[string]interface{}{
+		"userId":   addBudgetServiceRequestData.UserId,
+		"category": addBudgetServiceRequestData.Category,
+		"period":   addBudgetServiceRequestData.Period,
+	}
+	result, err := s.budgetRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddBudgetResponse",
+			addBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) != 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddBudgetResponse",
+			addBudgetServiceResponse{
+				Message: "A budget for this category and period already exists!",
+				Success: false,
+				Status: http.StatusConflict,
+			},
+		)
+		return
+	}
+
+	now := time.Now().UTC()
+	budget := models.Budget{
+		BudgetId:  uuid.New().String(),
+		UserId:    addBudgetServiceRequestData.UserId,
+		Category:  addBudgetServiceRequestData.Category,
+		Amount:    amount,
+		Currency:  addBudgetServiceRequestData.Currency,
+		Period:    addBudgetServiceRequestData.Period,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	result, err = s.budgetRepo.Insert(context.Background(), budget)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddBudgetResponse",
+			addBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AddBudgetResponse",
+		addBudgetServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			BudgetId: budget.BudgetId,
+		},
+	)
+}
+
+type updateBudgetServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	BudgetId	string		`json:"budgetId"`
+	Category	string		`json:"category"`
+	Amount		json.Number	`json:"amount"`
+	Currency	string		`json:"currency"`
+	Period		string		`json:"period"`
+}
+
+type updateBudgetServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleUpdateBudget(data []byte, replyTo string, correlationId string) {
+	updateBudgetServiceRequestData := updateBudgetServiceRequest{}
+	err := json.Unmarshal(data, &updateBudgetServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateBudgetResponse",
+			updateBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	amount, err := money.Parse(updateBudgetServiceRequestData.Amount.String(), updateBudgetServiceRequestData.Currency)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateBudgetResponse",
+			updateBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":   updateBudgetServiceRequestData.UserId,
+		"budgetId": updateBudgetServiceRequestData.BudgetId,
+	}
+	result, err := s.budgetRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateBudgetResponse",
+			updateBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateBudgetResponse",
+			updateBudgetServiceResponse{
+				Message: "Budget not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	category, err := s.resolveCategory(context.Background(), updateBudgetServiceRequestData.UserId, updateBudgetServiceRequestData.Category)
+	if err == errUnknownCategory {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateBudgetResponse",
+			updateBudgetServiceResponse{
+				Message: "Category is unknown!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateBudgetResponse",
+			updateBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	updateBudgetServiceRequestData.Category = category
+
+	duplicateFilter := map[string]interface{}{
+		"userId":   updateBudgetServiceRequestData.UserId,
+		"category": updateBudgetServiceRequestData.Category,
+		"period":   updateBudgetServiceRequestData.Period,
+		"budgetId": map[string]interface{}{"$ne": updateBudgetServiceRequestData.BudgetId},
+	}
+	result, err = s.budgetRepo.Find(context.Background(), duplicateFilter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateBudgetResponse",
+			updateBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) != 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateBudgetResponse",
+			updateBudgetServiceResponse{
+				Message: "A budget for this category and period already exists!",
+				Success: false,
+				Status: http.StatusConflict,
+			},
+		)
+		return
+	}
+
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{
+			"category":  updateBudgetServiceRequestData.Category,
+			"amount":    amount,
+			"currency":  updateBudgetServiceRequestData.Currency,
+			"period":    updateBudgetServiceRequestData.Period,
+			"updatedAt": time.Now().UTC(),
+		},
+	}
+	result, err = s.budgetRepo.Update(context.Background(), filter, update)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateBudgetResponse",
+			updateBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"UpdateBudgetResponse",
+		updateBudgetServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type removeBudgetServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	BudgetId	string	`json:"budgetId"`
+}
+
+type removeBudgetServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleRemoveBudget(data []byte, replyTo string, correlationId string) {
+	removeBudgetServiceRequestData := removeBudgetServiceRequest{}
+	err := json.Unmarshal(data, &removeBudgetServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveBudgetResponse",
+			removeBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":   removeBudgetServiceRequestData.UserId,
+		"budgetId": removeBudgetServiceRequestData.BudgetId,
+	}
+	result, err := s.budgetRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveBudgetResponse",
+			removeBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveBudgetResponse",
+			removeBudgetServiceResponse{
+				Message: "Budget not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	result, err = s.budgetRepo.Delete(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveBudgetResponse",
+			removeBudgetServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RemoveBudgetResponse",
+		removeBudgetServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type getBudgetStatusServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	BudgetId	string		`json:"budgetId"`
+	Date		time.Time	`json:"date"`
+}
+
+type getBudgetStatusServiceResponse struct {
+	Message	string						`json:"message"`
+	Success	bool						`json:"success"`
+	Budgets	[]map[string]interface{}	`json:"budgets"`
+}
+
+func (s *ExpenseService) HandleGetBudgetStatus(data []byte, replyTo string, correlationId string) {
+	getBudgetStatusServiceRequestData := getBudgetStatusServiceRequest{}
+	err := json.Unmarshal(data, &getBudgetStatusServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetBudgetStatusResponse",
+			getBudgetStatusServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	date := getBudgetStatusServiceRequestData.Date
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+
+	filter := map[string]interface{}{
+		"userId": getBudgetStatusServiceRequestData.UserId,
+	}
+	if getBudgetStatusServiceRequestData.BudgetId != "" {
+		filter["budgetId"] = getBudgetStatusServiceRequestData.BudgetId
+	}
+	result, err := s.budgetRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetBudgetStatusResponse",
+			getBudgetStatusServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	statuses := make([]map[string]interface{}, 0, len(result.Data))
+	for _, document := range result.Data {
+		budget := models.Budget{}
+		if err := decodeDocument(document, &budget); err != nil {
+			log.Println(err)
+			continue
+		}
+		status, err := s.budgetStatus(context.Background(), budget, date)
+		if err != nil {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"GetBudgetStatusResponse",
+				getBudgetStatusServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+		statuses = append(statuses, status)
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetBudgetStatusResponse",
+		getBudgetStatusServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Budgets: statuses,
+		},
+	)
+}
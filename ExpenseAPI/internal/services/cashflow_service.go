@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/money"
+	"log"
+	"sort"
+	"time"
+)
+
+// cashFlowPeriod returns the bounds [start, end) of the weekly, monthly or
+// yearly period that contains the given date.
+func cashFlowPeriod(period string, date time.Time) (time.Time, time.Time) {
+	if period == "yearly" {
+		start := time.Date(date.UTC().Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0)
+	}
+	return budgetPeriod(period, date)
+}
+
+type cashFlowPeriodServiceResponse struct {
+	PeriodStart	time.Time	`json:"periodStart"`
+	PeriodEnd	time.Time	`json:"periodEnd"`
+	Income		string		`json:"income"`
+	Expenses	string		`json:"expenses"`
+	Net			string		`json:"net"`
+}
+
+type getCashFlowServiceRequest struct {
+	Action			string		`json:"action"`
+	UserId			string		`json:"userId"`
+	From			*time.Time	`json:"from"`
+	To				*time.Time	`json:"to"`
+	Period			string		`json:"period"`
+	BaseCurrency	string		`json:"baseCurrency"`
+}
+
+type getCashFlowServiceResponse struct {
+	Message			string							`json:"message"`
+	Success			bool							`json:"success"`
+	BaseCurrency	string							`json:"baseCurrency"`
+	Periods			[]cashFlowPeriodServiceResponse	`json:"periods"`
+	Income			string							`json:"income"`
+	Expenses		string							`json:"expenses"`
+	Net				string							`json:"net"`
+	MissingRates	bool							`json:"missingRates"`
+}
+
+func (s *ExpenseService) HandleGetCashFlow(data []byte, replyTo string, correlationId string) {
+	getCashFlowServiceRequestData := getCashFlowServiceRequest{}
+	err := json.Unmarshal(data, &getCashFlowServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetCashFlowResponse",
+			getCashFlowServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	// Transfers move money between the user's own accounts and are left out.
+	filter := map[string]interface{}{
+		"userId":    getCashFlowServiceRequestData.UserId,
+		"type":      map[string]interface{}{"$ne": "transfer"},
+		"deletedAt": notDeleted(),
+	}
+	if getCashFlowServiceRequestData.From != nil || getCashFlowServiceRequestData.To != nil {
+		dateRange := map[string]interface{}{}
+		if getCashFlowServiceRequestData.From != nil {
+			dateRange["$gte"] = *getCashFlowServiceRequestData.From
+		}
+		if getCashFlowServiceRequestData.To != nil {
+			dateRange["$lt"] = *getCashFlowServiceRequestData.To
+		}
+		filter["date"] = dateRange
+	}
+	result, err := s.mongoDBRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetCashFlowResponse",
+			getCashFlowServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	baseCurrency := getCashFlowServiceRequestData.BaseCurrency
+	if !money.IsCurrency(baseCurrency) {
+		baseCurrency = money.DefaultCurrency()
+	}
+
+	type cashFlow struct {
+		end			time.Time
+		income		int64
+		expenses	int64
+	}
+	flows := make(map[time.Time]*cashFlow)
+	totalIncome := int64(0)
+	totalExpenses := int64(0)
+	complete := true
+	for _, expense := range result.Data {
+		amount, currency := documentAmount(expense)
+		date := expenseDate(expense)
+		converted, _, err := s.rates.Convert(amount, currency, baseCurrency, date)
+		if err != nil {
+			complete = false
+			continue
+		}
+
+		start, end := cashFlowPeriod(getCashFlowServiceRequestData.Period, date)
+		flow, exists := flows[start]
+		if !exists {
+			flow = &cashFlow{end: end}
+			flows[start] = flow
+		}
+		if entryType(expense) == "income" {
+			flow.income += converted
+			totalIncome += converted
+		} else {
+			flow.expenses += converted
+			totalExpenses += converted
+		}
+	}
+
+	starts := make([]time.Time, 0, len(flows))
+	for start := range flows {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+	periods := make([]cashFlowPeriodServiceResponse, 0, len(starts))
+	for _, start := range starts {
+		flow := flows[start]
+		periods = append(periods, cashFlowPeriodServiceResponse{
+			PeriodStart: start,
+			PeriodEnd:   flow.end,
+			Income:      money.Format(flow.income, baseCurrency),
+			Expenses:    money.Format(flow.expenses, baseCurrency),
+			Net:         money.Format(flow.income-flow.expenses, baseCurrency),
+		})
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetCashFlowResponse",
+		getCashFlowServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			BaseCurrency: baseCurrency,
+			Periods: periods,
+			Income: money.Format(totalIncome, baseCurrency),
+			Expenses: money.Format(totalExpenses, baseCurrency),
+			Net: money.Format(totalIncome-totalExpenses, baseCurrency),
+			MissingRates: !complete,
+		},
+	)
+}
@@ -0,0 +1,1075 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense/internal/models"
+	"expense/internal/repositories"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errUnknownCategory = errors.New("Category is unknown!")
+
+type defaultCategory struct {
+	name   string
+	parent string
+	color  string
+	icon   string
+}
+
+var defaultCategories = []defaultCategory{
+	{name: "Food", color: "#F59E0B", icon: "utensils"},
+	{name: "Groceries", parent: "Food", color: "#F59E0B", icon: "shopping-cart"},
+	{name: "Restaurants", parent: "Food", color: "#F59E0B", icon: "coffee"},
+	{name: "Housing", color: "#8B5CF6", icon: "home"},
+	{name: "Utilities", parent: "Housing", color: "#8B5CF6", icon: "zap"},
+	{name: "Transport", color: "#3B82F6", icon: "car"},
+	{name: "Health", color: "#EF4444", icon: "heart"},
+	{name: "Entertainment", color: "#EC4899", icon: "film"},
+	{name: "Shopping", color: "#10B981", icon: "shopping-bag"},
+	{name: "Travel", color: "#06B6D4", icon: "plane"},
+	{name: "Other", color: "#6B7280", icon: "tag"},
+}
+
+func normalizeCategoryName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ensureCategories seeds the default taxonomy for users without categories.
+// Category names already used by their expenses, recurring expenses and
+// budgets are kept as root categories, so entries recorded before categories
+// existed stay valid. Their spellings are then rewritten to the names of their
+// categories, so "food" and "Food" are counted as one category.
+func (s *ExpenseService) ensureCategories(ctx context.Context, userId string) error {
+	result, err := s.categoryRepo.Find(ctx, map[string]interface{}{"userId": userId})
+	if !result.Success {
+		return err
+	}
+	if len(result.Data) != 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	categoryIds := make(map[string]string)
+	names := make(map[string]string)
+	for _, seed := range defaultCategories {
+		category := models.Category{
+			CategoryId:     uuid.New().String(),
+			UserId:         userId,
+			Name:           seed.name,
+			NormalizedName: normalizeCategoryName(seed.name),
+			ParentId:       categoryIds[seed.parent],
+			Color:          seed.color,
+			Icon:           seed.icon,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		names[category.NormalizedName] = category.Name
+		result, err := s.categoryRepo.Insert(ctx, category)
+		// Another request seeding the same user got there first.
+		if err == repositories.ErrDuplicate {
+			continue
+		}
+		if !result.Success {
+			return err
+		}
+		categoryIds[seed.name] = category.CategoryId
+	}
+
+	spellings := make(map[string]bool)
+	for _, repo := range []*repositories.MongoDBRepository{s.mongoDBRepo, s.recurringRepo, s.budgetRepo} {
+		result, err := repo.Find(ctx, map[string]interface{}{"userId": userId})
+		if !result.Success {
+			return err
+		}
+		for _, document := range result.Data {
+			name, _ := document["category"].(string)
+			if normalizeCategoryName(name) != "" {
+				spellings[name] = true
+			}
+		}
+	}
+
+	for name := range spellings {
+		normalizedName := normalizeCategoryName(name)
+		if _, exists := names[normalizedName]; exists {
+			continue
+		}
+		category := models.Category{
+			CategoryId:     uuid.New().String(),
+			UserId:         userId,
+			Name:           strings.TrimSpace(name),
+			NormalizedName: normalizedName,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		names[normalizedName] = category.Name
+		result, err := s.categoryRepo.Insert(ctx, category)
+		if err == repositories.ErrDuplicate {
+			continue
+		}
+		if !result.Success {
+			return err
+		}
+	}
+
+	for name := range spellings {
+		if canonical := names[normalizeCategoryName(name)]; canonical != name {
+			if err := s.renameCategoryReferences(ctx, userId, name, canonical); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// userCategories returns the categories of a user. Users without any, e.g.
+// because they registered before categories existed, are seeded on first use.
+func (s *ExpenseService) userCategories(ctx context.Context, userId string) ([]models.Category, error) {
+	result, err := s.categoryRepo.Find(ctx, map[string]interface{}{"userId": userId})
+	if !result.Success {
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		if err := s.ensureCategories(ctx, userId); err != nil {
+			return nil, err
+		}
+		result, err = s.categoryRepo.Find(ctx, map[string]interface{}{"userId": userId})
+		if !result.Success {
+			return nil, err
+		}
+	}
+	categories := make([]models.Category, 0, len(result.Data))
+	for _, document := range result.Data {
+		category := models.Category{}
+		if err := decodeDocument(document, &category); err != nil {
+			return nil, err
+		}
+		category.NormalizedName = normalizeCategoryName(category.Name)
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+func (s *ExpenseService) findCategory(ctx context.Context, userId string, categoryId string) (*models.Category, error) {
+	categories, err := s.userCategories(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range categories {
+		if categories[i].CategoryId == categoryId {
+			return &categories[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// resolveCategory maps a category name given by the user onto the stored
+// spelling, so "food" and "Food" end up as the same category.
+func (s *ExpenseService) resolveCategory(ctx context.Context, userId string, name string) (string, error) {
+	categories, err := s.userCategories(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	normalizedName := normalizeCategoryName(name)
+	for _, category := range categories {
+		if category.NormalizedName == normalizedName {
+			return category.Name, nil
+		}
+	}
+	return "", errUnknownCategory
+}
+
+// resolveEntryCategory resolves the category of an entry. Income and transfer
+// entries may have no category.
+func (s *ExpenseService) resolveEntryCategory(ctx context.Context, userId string, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	return s.resolveCategory(ctx, userId, name)
+}
+
+// categoryWithDescendants returns the names of a category and all categories below it.
+func (s *ExpenseService) categoryWithDescendants(ctx context.Context, userId string, name string) ([]string, error) {
+	categories, err := s.userCategories(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[string][]models.Category)
+	var root *models.Category
+	for i := range categories {
+		children[categories[i].ParentId] = append(children[categories[i].ParentId], categories[i])
+		if categories[i].NormalizedName == normalizeCategoryName(name) {
+			root = &categories[i]
+		}
+	}
+	if root == nil {
+		return []string{name}, nil
+	}
+
+	names := make([]string, 0)
+	pending := []models.Category{*root}
+	for len(pending) > 0 {
+		category := pending[0]
+		pending = pending[1:]
+		names = append(names, category.Name)
+		pending = append(pending, children[category.CategoryId]...)
+	}
+	return names, nil
+}
+
+// isDescendant reports whether candidateId lies below (or is) ancestorId.
+func isDescendant(categories []models.Category, candidateId string, ancestorId string) bool {
+	parents := make(map[string]string)
+	for _, category := range categories {
+		parents[category.CategoryId] = category.ParentId
+	}
+	for current, steps := candidateId, 0; current != "" && steps <= len(categories); current, steps = parents[current], steps+1 {
+		if current == ancestorId {
+			return true
+		}
+	}
+	return false
+}
+
+// renameCategoryReferences rewrites the category of every expense, recurring
+// expense, budget and rule of the user from one name to another.
+func (s *ExpenseService) renameCategoryReferences(ctx context.Context, userId string, from string, to string) error {
+	filter := map[string]interface{}{
+		"userId":   userId,
+		"category": from,
+	}
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{"category": to},
+	}
+	result, err := s.mongoDBRepo.Find(ctx, filter)
+	if !result.Success {
+		return err
+	}
+	histories := make([]models.ExpenseHistory, 0, len(result.Data))
+	for _, document := range result.Data {
+		expenseId, _ := document["expenseId"].(string)
+		histories = append(histories, newExpenseHistory(userId, expenseId, userId, "update", document, patchedDocument(document, map[string]interface{}{"category": to, "version": documentVersion(document) + 1})))
+	}
+	expenseUpdate := map[string]interface{}{
+		"$set": map[string]interface{}{"category": to},
+		"$inc": map[string]interface{}{"version": 1},
+	}
+	result, err = s.mongoDBRepo.UpdateMany(ctx, filter, expenseUpdate)
+	if !result.Success {
+		return err
+	}
+	if err := s.recordHistory(ctx, histories...); err != nil {
+		return err
+	}
+	result, err = s.recurringRepo.UpdateMany(ctx, filter, update)
+	if !result.Success {
+		return err
+	}
+	result, err = s.budgetRepo.UpdateMany(ctx, filter, update)
+	if !result.Success {
+		return err
+	}
+	result, err = s.ruleRepo.UpdateMany(ctx, filter, update)
+	if !result.Success {
+		return err
+	}
+	return nil
+}
+
+type getCategoryServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+}
+
+type getCategoryServiceResponse struct {
+	Message		string				`json:"message"`
+	Success		bool				`json:"success"`
+	Categories	[]models.Category	`json:"categories"`
+}
+
+func (s *ExpenseService) HandleGetCategory(data []byte, replyTo string, correlationId string) {
+	getCategoryServiceRequestData := getCategoryServiceRequest{}
+	err := json.Unmarshal(data, &getCategoryServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetCategoryResponse",
+			getCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	categories, err := s.userCategories(context.Background(), getCategoryServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetCategoryResponse",
+			getCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetCategoryResponse",
+		getCategoryServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Categories: categories,
+		},
+	)
+}
+
+type addCategoryServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	Name		string	`json:"name"`
+	ParentId	string	`json:"parentId"`
+	Color		string	`json:"color"`
+	Icon		string	`json:"icon"`
+}
+
+type addCategoryServiceResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	Status		int		`json:"status,omitempty"`
+	CategoryId	string	`json:"categoryId"`
+}
+
+func (s *ExpenseService) HandleAddCategory(data []byte, replyTo string, correlationId string) {
+	addCategoryServiceRequestData := addCategoryServiceRequest{}
+	err := json.Unmarshal(data, &addCategoryServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddCategoryResponse",
+			addCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	_, err = s.resolveCategory(context.Background(), addCategoryServiceRequestData.UserId, addCategoryServiceRequestData.Name)
+	if err == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddCategoryResponse",
+			addCategoryServiceResponse{
+				Message: "Category already exists!",
+				Success: false,
+				Status: http.StatusConflict,
+			},
+		)
+		return
+	}
+	if err != errUnknownCategory {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddCategoryResponse",
+			addCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	if addCategoryServiceRequestData.ParentId != "" {
+		parent, err := s.findCategory(context.Background(), addCategoryServiceRequestData.UserId, addCategoryServiceRequestData.ParentId)
+		if err != nil {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"AddCategoryResponse",
+				addCategoryServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+		if parent == nil {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"AddCategoryResponse",
+				addCategoryServiceResponse{
+					Message: "Parent category not found!",
+					Success: false,
+					Status: http.StatusNotFound,
+				},
+			)
+			return
+		}
+		addCategoryServiceRequestData.ParentId = parent.CategoryId
+	}
+
+	now := time.Now().UTC()
+	category := models.Category{
+		CategoryId:     uuid.New().String(),
+		UserId:         addCategoryServiceRequestData.UserId,
+		Name:           addCategoryServiceRequestData.Name,
+		NormalizedName: normalizeCategoryName(addCategoryServiceRequestData.Name),
+		ParentId:       addCategoryServiceRequestData.ParentId,
+		Color:          addCategoryServiceRequestData.Color,
+		Icon:           addCategoryServiceRequestData.Icon,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	result, err := s.categoryRepo.Insert(context.Background(), category)
+	if err == repositories.ErrDuplicate {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddCategoryResponse",
+			addCategoryServiceResponse{
+				Message: "Category already exists!",
+				Success: false,
+				Status: http.StatusConflict,
+			},
+		)
+		return
+	}
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddCategoryResponse",
+			addCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AddCategoryResponse",
+		addCategoryServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			CategoryId: category.CategoryId,
+		},
+	)
+}
+
+type updateCategoryServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	CategoryId	string	`json:"categoryId"`
+	Name		string	`json:"name"`
+	ParentId	string	`json:"parentId"`
+	Color		string	`json:"color"`
+	Icon		string	`json:"icon"`
+}
+
+type updateCategoryServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleUpdateCategory(data []byte, replyTo string, correlationId string) {
+	updateCategoryServiceRequestData := updateCategoryServiceRequest{}
+	err := json.Unmarshal(data, &updateCategoryServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateCategoryResponse",
+			updateCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	ctx := context.Background()
+	categories, err := s.userCategories(ctx, updateCategoryServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateCategoryResponse",
+			updateCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	var category *models.Category
+	for i := range categories {
+		if categories[i].CategoryId == updateCategoryServiceRequestData.CategoryId {
+			category = &categories[i]
+		} else if categories[i].NormalizedName == normalizeCategoryName(updateCategoryServiceRequestData.Name) {
+				SendResponse(
+					s.channel,
+					replyTo,
+					correlationId,
+					"UpdateCategoryResponse",
+					updateCategoryServiceResponse{
+						Message: "Category already exists!",
+						Success: false,
+						Status: http.StatusConflict,
+					},
+				)
+				return
+		}
+	}
+	if category == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateCategoryResponse",
+			updateCategoryServiceResponse{
+				Message: "Category not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	if updateCategoryServiceRequestData.ParentId != "" {
+		parentExists := false
+		for _, candidate := range categories {
+			if candidate.CategoryId == updateCategoryServiceRequestData.ParentId {
+				parentExists = true
+			}
+		}
+		if !parentExists {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"UpdateCategoryResponse",
+				updateCategoryServiceResponse{
+					Message: "Parent category not found!",
+					Success: false,
+					Status: http.StatusNotFound,
+				},
+			)
+			return
+		}
+		if isDescendant(categories, updateCategoryServiceRequestData.ParentId, category.CategoryId) {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"UpdateCategoryResponse",
+				updateCategoryServiceResponse{
+					Message: "A category cannot be moved below itself!",
+					Success: false,
+					Status: http.StatusBadRequest,
+				},
+			)
+			return
+		}
+	}
+
+	previousName := category.Name
+	filter := map[string]interface{}{
+		"userId":     updateCategoryServiceRequestData.UserId,
+		"categoryId": updateCategoryServiceRequestData.CategoryId,
+	}
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{
+			"name":           updateCategoryServiceRequestData.Name,
+			"normalizedName": normalizeCategoryName(updateCategoryServiceRequestData.Name),
+			"parentId":       updateCategoryServiceRequestData.ParentId,
+			"color":          updateCategoryServiceRequestData.Color,
+			"icon":           updateCategoryServiceRequestData.Icon,
+			"updatedAt":      time.Now().UTC(),
+		},
+	}
+	result, err := s.categoryRepo.Update(ctx, filter, update)
+	if err == repositories.ErrDuplicate {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateCategoryResponse",
+			updateCategoryServiceResponse{
+				Message: "Category already exists!",
+				Success: false,
+				Status: http.StatusConflict,
+			},
+		)
+		return
+	}
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateCategoryResponse",
+			updateCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	if previousName != updateCategoryServiceRequestData.Name {
+		err = s.renameCategoryReferences(ctx, updateCategoryServiceRequestData.UserId, previousName, updateCategoryServiceRequestData.Name)
+		if err != nil {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"UpdateCategoryResponse",
+				updateCategoryServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"UpdateCategoryResponse",
+		updateCategoryServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type removeCategoryServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	CategoryId	string	`json:"categoryId"`
+}
+
+type removeCategoryServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleRemoveCategory(data []byte, replyTo string, correlationId string) {
+	removeCategoryServiceRequestData := removeCategoryServiceRequest{}
+	err := json.Unmarshal(data, &removeCategoryServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveCategoryResponse",
+			removeCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	ctx := context.Background()
+	categories, err := s.userCategories(ctx, removeCategoryServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveCategoryResponse",
+			removeCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	var category *models.Category
+	for i := range categories {
+		if categories[i].CategoryId == removeCategoryServiceRequestData.CategoryId {
+			category = &categories[i]
+		}
+		if categories[i].ParentId == removeCategoryServiceRequestData.CategoryId {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"RemoveCategoryResponse",
+				removeCategoryServiceResponse{
+					Message: "Category has subcategories!",
+					Success: false,
+					Status: http.StatusConflict,
+				},
+			)
+			return
+		}
+	}
+	if category == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveCategoryResponse",
+			removeCategoryServiceResponse{
+				Message: "Category not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	usageFilter := map[string]interface{}{
+		"userId":   removeCategoryServiceRequestData.UserId,
+		"category": category.Name,
+	}
+	for _, repo := range []*repositories.MongoDBRepository{s.mongoDBRepo, s.recurringRepo, s.budgetRepo, s.ruleRepo} {
+		result, err := repo.Find(ctx, usageFilter)
+		if !result.Success {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"RemoveCategoryResponse",
+				removeCategoryServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+		if len(result.Data) != 0 {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"RemoveCategoryResponse",
+				removeCategoryServiceResponse{
+					Message: "Category is in use! Merge it into another category instead.",
+					Success: false,
+					Status: http.StatusConflict,
+				},
+			)
+			return
+		}
+	}
+
+	filter := map[string]interface{}{
+		"userId":     removeCategoryServiceRequestData.UserId,
+		"categoryId": removeCategoryServiceRequestData.CategoryId,
+	}
+	result, err := s.categoryRepo.Delete(ctx, filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveCategoryResponse",
+			removeCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RemoveCategoryResponse",
+		removeCategoryServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type mergeCategoryServiceRequest struct {
+	Action				string	`json:"action"`
+	UserId				string	`json:"userId"`
+	SourceCategoryId	string	`json:"sourceCategoryId"`
+	TargetCategoryId	string	`json:"targetCategoryId"`
+}
+
+type mergeCategoryServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+// HandleMergeCategory moves everything that refers to the source category over
+// to the target category and removes the source afterwards.
+func (s *ExpenseService) HandleMergeCategory(data []byte, replyTo string, correlationId string) {
+	mergeCategoryServiceRequestData := mergeCategoryServiceRequest{}
+	err := json.Unmarshal(data, &mergeCategoryServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"MergeCategoryResponse",
+			mergeCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	ctx := context.Background()
+	userId := mergeCategoryServiceRequestData.UserId
+	categories, err := s.userCategories(ctx, userId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"MergeCategoryResponse",
+			mergeCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	var source, target *models.Category
+	for i := range categories {
+		switch categories[i].CategoryId {
+		case mergeCategoryServiceRequestData.SourceCategoryId:
+			source = &categories[i]
+		case mergeCategoryServiceRequestData.TargetCategoryId:
+			target = &categories[i]
+		}
+	}
+	if source == nil || target == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"MergeCategoryResponse",
+			mergeCategoryServiceResponse{
+				Message: "Category not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+	if isDescendant(categories, target.CategoryId, source.CategoryId) {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"MergeCategoryResponse",
+			mergeCategoryServiceResponse{
+				Message: "A category cannot be merged into itself or its subcategories!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+
+	// A budget of the source is dropped when the target already has one for the same period.
+	result, err := s.budgetRepo.Find(ctx, map[string]interface{}{"userId": userId, "category": source.Name})
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"MergeCategoryResponse",
+			mergeCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	for _, budget := range result.Data {
+		duplicateFilter := map[string]interface{}{
+			"userId":   userId,
+			"category": target.Name,
+			"period":   budget["period"],
+		}
+		duplicates, err := s.budgetRepo.Find(ctx, duplicateFilter)
+		if !duplicates.Success {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"MergeCategoryResponse",
+				mergeCategoryServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+		if len(duplicates.Data) != 0 {
+			deleteResult, err := s.budgetRepo.Delete(ctx, map[string]interface{}{"userId": userId, "budgetId": budget["budgetId"]})
+			if !deleteResult.Success {
+				log.Println(err)
+				SendResponse(
+					s.channel,
+					replyTo,
+					correlationId,
+					"MergeCategoryResponse",
+					mergeCategoryServiceResponse{
+						Message: "An error occured!",
+						Success: false,
+					},
+				)
+				return
+			}
+		}
+	}
+
+	err = s.renameCategoryReferences(ctx, userId, source.Name, target.Name)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"MergeCategoryResponse",
+			mergeCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	childFilter := map[string]interface{}{
+		"userId":   userId,
+		"parentId": source.CategoryId,
+	}
+	childUpdate := map[string]interface{}{
+		"$set": map[string]interface{}{"parentId": target.CategoryId},
+	}
+	result, err = s.categoryRepo.UpdateMany(ctx, childFilter, childUpdate)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"MergeCategoryResponse",
+			mergeCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	result, err = s.categoryRepo.Delete(ctx, map[string]interface{}{"userId": userId, "categoryId": source.CategoryId})
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"MergeCategoryResponse",
+			mergeCategoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"MergeCategoryResponse",
+		mergeCategoryServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type seedCategoriesServiceRequest struct {
+	UserId	string	`json:"userId"`
+}
+
+// HandleSeedCategories is triggered by the user service after a registration.
+// It is a one-way message, so there is no response.
+func (s *ExpenseService) HandleSeedCategories(data []byte) {
+	seedCategoriesServiceRequestData := seedCategoriesServiceRequest{}
+	err := json.Unmarshal(data, &seedCategoriesServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if seedCategoriesServiceRequestData.UserId == "" {
+		return
+	}
+	if err := s.ensureCategories(context.Background(), seedCategoriesServiceRequestData.UserId); err != nil {
+		log.Println(err)
+	}
+}
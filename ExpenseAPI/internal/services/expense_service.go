@@ -2,20 +2,38 @@ package services
 
 import (
 	"expense/internal/models"
+	"expense/internal/money"
+	"expense/internal/rates"
+	"expense/internal/rules"
 	"expense/internal/repositories"
 	"log"
+	"net/http"
 	"os"
 	"encoding/json"
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ExpenseService struct {
 	connection	*amqp.Connection
 	channel		*amqp.Channel
 	mongoDBRepo	*repositories.MongoDBRepository
+	ratesRepo	*repositories.MongoDBRepository
+	recurringRepo	*repositories.MongoDBRepository
+	budgetRepo	*repositories.MongoDBRepository
+	categoryRepo	*repositories.MongoDBRepository
+	receiptRepo	*repositories.MongoDBRepository
+	receiptStore	repositories.BlobStore
+	accountRepo	*repositories.MongoDBRepository
+	ruleRepo	*repositories.MongoDBRepository
+	historyRepo	*repositories.MongoDBRepository
+	rates		*rates.Table
+	schedulerStop	chan struct{}
 }
 
 func NewExpenseService(connection *amqp.Connection, uri string, databaseName string, collectionName string) (*ExpenseService, error) {
@@ -31,16 +49,75 @@ func NewExpenseService(connection *amqp.Connection, uri string, databaseName str
 		return nil, err
 	}
 
-	return &ExpenseService{
+	receiptStore, err := repositories.NewGridFSBlobStore(repo, "receipts")
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	service := &ExpenseService{
 		connection: 	connection,
 		channel: 		channel,
 		mongoDBRepo: 	repo,
-	}, nil
+		ratesRepo: 		repo.WithCollection("rates"),
+		recurringRepo: 	repo.WithCollection("recurring_expenses"),
+		budgetRepo: 	repo.WithCollection("budgets"),
+		categoryRepo: 	repo.WithCollection("categories"),
+		receiptRepo: 	repo.WithCollection("receipts"),
+		receiptStore: 	receiptStore,
+		accountRepo: 	repo.WithCollection("accounts"),
+		ruleRepo: 		repo.WithCollection("rules"),
+		historyRepo: 	repo.WithCollection("expense_history"),
+		rates: 			rates.NewTable(),
+		schedulerStop: 	make(chan struct{}),
+	}
+
+	// Multikey index for tag filtering; "tags" is the only array field in it.
+	if err := repo.CreateIndex(context.Background(), bson.D{{Key: "userId", Value: 1}, {Key: "tags", Value: 1}}); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	// Searches match words in the description, payee and notes of the user's entries.
+	if err := repo.CreateIndex(context.Background(), bson.D{{Key: "userId", Value: 1}, {Key: "description", Value: "text"}, {Key: "payee", Value: "text"}, {Key: "notes", Value: "text"}}); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	// The purge looks up entries whose retention in the trash has passed.
+	if err := repo.CreateIndex(context.Background(), bson.D{{Key: "deletedAt", Value: 1}}); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	// Category names are unique per user regardless of case, even for concurrent writes.
+	if err := service.categoryRepo.CreateIndex(context.Background(), bson.D{{Key: "userId", Value: 1}, {Key: "normalizedName", Value: 1}}, options.Index().SetUnique(true)); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	// Histories are looked up per entry of the user.
+	if err := service.historyRepo.CreateIndex(context.Background(), bson.D{{Key: "userId", Value: 1}, {Key: "expenseId", Value: 1}}); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	// Imports look up the bank's transaction ids to skip statements imported before.
+	// Entries without one are left out, so that only imported transactions are unique.
+	externalIdIndex := options.Index().SetUnique(true).SetPartialFilterExpression(bson.D{{Key: "externalId", Value: bson.D{{Key: "$type", Value: "string"}}}})
+	if err := repo.CreateIndex(context.Background(), bson.D{{Key: "userId", Value: 1}, {Key: "externalId", Value: 1}}, externalIdIndex); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	if err := service.loadRates(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return service, nil
 }
 
 func (s *ExpenseService) Start() {
 	defer s.connection.Close()
 	defer s.channel.Close()
+
+	go s.runScheduler()
 	
 	rabbitmqURI := os.Getenv("RABBITMQ_URI")
 	connection, err := amqp.Dial(rabbitmqURI)
@@ -79,6 +156,87 @@ func (s *ExpenseService) Start() {
 				s.HandleUpdateExpense(message.Body, message.ReplyTo, message.CorrelationId)
 			case "RemoveExpense":
 				s.HandleRemoveExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "BatchExpense":
+				s.HandleBatchExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetTrash":
+				s.HandleGetTrash(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RestoreExpense":
+				s.HandleRestoreExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetExpenseHistory":
+				s.HandleGetExpenseHistory(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RevertExpense":
+				s.HandleRevertExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "ExportExpenses":
+				// Exports wait for the client between chunks, so they must not hold up other requests.
+				go s.HandleExportExpenses(message.Body, message.ReplyTo, message.CorrelationId)
+			case "ImportExpenses":
+				s.HandleImportExpenses(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetReport":
+				s.HandleGetReport(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetForecast":
+				s.HandleGetForecast(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetAnomaly":
+				s.HandleGetAnomaly(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AcknowledgeAnomaly":
+				s.HandleAcknowledgeAnomaly(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetCashFlow":
+				s.HandleGetCashFlow(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetRecurringExpense":
+				s.HandleGetRecurringExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AddRecurringExpense":
+				s.HandleAddRecurringExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "UpdateRecurringExpense":
+				s.HandleUpdateRecurringExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RemoveRecurringExpense":
+				s.HandleRemoveRecurringExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetBudget":
+				s.HandleGetBudget(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AddBudget":
+				s.HandleAddBudget(message.Body, message.ReplyTo, message.CorrelationId)
+			case "UpdateBudget":
+				s.HandleUpdateBudget(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RemoveBudget":
+				s.HandleRemoveBudget(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetBudgetStatus":
+				s.HandleGetBudgetStatus(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetCategory":
+				s.HandleGetCategory(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AddCategory":
+				s.HandleAddCategory(message.Body, message.ReplyTo, message.CorrelationId)
+			case "UpdateCategory":
+				s.HandleUpdateCategory(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RemoveCategory":
+				s.HandleRemoveCategory(message.Body, message.ReplyTo, message.CorrelationId)
+			case "MergeCategory":
+				s.HandleMergeCategory(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetReceipt":
+				s.HandleGetReceipt(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AddReceipt":
+				s.HandleAddReceipt(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RemoveReceipt":
+				s.HandleRemoveReceipt(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetAccount":
+				s.HandleGetAccount(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AddAccount":
+				s.HandleAddAccount(message.Body, message.ReplyTo, message.CorrelationId)
+			case "UpdateAccount":
+				s.HandleUpdateAccount(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RemoveAccount":
+				s.HandleRemoveAccount(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetAccountBalance":
+				s.HandleGetAccountBalance(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetRule":
+				s.HandleGetRule(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AddRule":
+				s.HandleAddRule(message.Body, message.ReplyTo, message.CorrelationId)
+			case "UpdateRule":
+				s.HandleUpdateRule(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RemoveRule":
+				s.HandleRemoveRule(message.Body, message.ReplyTo, message.CorrelationId)
+			case "ApplyRules":
+				s.HandleApplyRules(message.Body, message.ReplyTo, message.CorrelationId)
+			case "SeedCategories":
+				s.HandleSeedCategories(message.Body)
 			default:
 				log.Printf("Action (%s) is unknown!", action)
 		}
@@ -87,6 +245,7 @@ func (s *ExpenseService) Start() {
 
 func (s *ExpenseService) Stop() {
 	log.Println("Expense service is being stopping.")
+	close(s.schedulerStop)
 	if err := s.channel.Close(); err != nil {
 		log.Println(err)
 	}
@@ -97,12 +256,141 @@ type getExpenseServiceRequest struct {
 	Action   string 				`json:"action"`
 	UserId	 string 				`json:"userId"`
 	Filter   map[string]interface{} `json:"filter"`
+	From	 *time.Time				`json:"from"`
+	To		 *time.Time				`json:"to"`
+	Tags	 []string				`json:"tags"`
+	TagMode	 string					`json:"tagMode"`
+	Type	 string					`json:"type"`
+	Query	 string					`json:"query"`
+	Limit	 int					`json:"limit"`
+	Cursor	 string					`json:"cursor"`
+	SortField	 string				`json:"sortField"`
+	SortDirection int				`json:"sortDirection"`
+	Fields	 []string				`json:"fields"`
+	BaseCurrency string				`json:"baseCurrency"`
 }
 
 type getExpenseServiceResponse struct {
 	Message  string 		  			`json:"message"`
 	Success  bool 			  			`json:"success"`
 	Expenses []map[string]interface{}	`json:"expenses"`
+	BaseCurrency string					`json:"baseCurrency"`
+	Total	 string						`json:"total"`
+	Income	 string						`json:"income"`
+	Net		 string						`json:"net"`
+	Totals	 map[string]string			`json:"totals"`
+	MissingRates bool					`json:"missingRates"`
+	NextCursor	 string					`json:"nextCursor,omitempty"`
+	Status	 int						`json:"status,omitempty"`
+}
+
+// expenseQueryFilter builds the query of GET /expense and of exports. Like a
+// budget, a category also covers the entries of its subcategories.
+func (s *ExpenseService) expenseQueryFilter(ctx context.Context, request getExpenseServiceRequest) (map[string]interface{}, error) {
+	filter := map[string]interface{}{
+		"userId":    request.UserId,
+		"deletedAt": notDeleted(),
+	}
+	for key, value := range request.Filter {
+		filter[key] = value
+	}
+	if name, ok := filter["category"].(string); ok {
+		category, err := s.resolveEntryCategory(ctx, request.UserId, name)
+		if err == errUnknownCategory {
+			return nil, &entryError{status: http.StatusBadRequest, message: "Category is unknown!"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		categories, err := s.categoryWithDescendants(ctx, request.UserId, category)
+		if err != nil {
+			return nil, err
+		}
+		filter["category"] = map[string]interface{}{"$in": categories}
+	}
+	// Entries stored before currencies were introduced are in the default currency.
+	if currency, ok := filter["currency"].(string); ok && currency == money.DefaultCurrency() {
+		filter["currency"] = map[string]interface{}{"$in": []interface{}{currency, "", nil}}
+	}
+	if request.From != nil || request.To != nil {
+		dateRange := map[string]interface{}{}
+		if request.From != nil {
+			dateRange["$gte"] = *request.From
+		}
+		if request.To != nil {
+			dateRange["$lt"] = *request.To
+		}
+		filter["date"] = dateRange
+	}
+	if len(request.Tags) != 0 {
+		operator := "$in"
+		if request.TagMode == "all" {
+			operator = "$all"
+		}
+		filter["tags"] = map[string]interface{}{operator: request.Tags}
+	}
+	switch request.Type {
+	case "expense":
+		filter["type"] = expenseTypeFilter()
+	case "income", "transfer":
+		filter["type"] = request.Type
+	}
+	if request.Query != "" {
+		filter["$text"] = map[string]interface{}{"$search": request.Query}
+	}
+	return filter, nil
+}
+
+// expenseTotalsPipeline sums the amounts per type, day and currency in the
+// database, like the report does, so that the totals of a page cover all
+// matching entries and not just the page.
+func expenseTotalsPipeline(filter map[string]interface{}) []interface{} {
+	return []interface{}{
+		map[string]interface{}{"$match": filter},
+		map[string]interface{}{"$group": map[string]interface{}{
+			"_id": map[string]interface{}{
+				"type":     "$type",
+				"currency": "$currency",
+				"day": map[string]interface{}{"$dateToString": map[string]interface{}{
+					"format": "%Y-%m-%d",
+					"date":   map[string]interface{}{"$ifNull": []interface{}{"$date", "$createdAt"}},
+				}},
+				// Legacy floating point amounts are summed apart from minor units.
+				"legacy": map[string]interface{}{"$eq": []interface{}{map[string]interface{}{"$type": "$amount"}, "double"}},
+			},
+			"amount": map[string]interface{}{"$sum": "$amount"},
+		}},
+		map[string]interface{}{"$project": map[string]interface{}{
+			"_id":      0,
+			"type":     "$_id.type",
+			"currency": "$_id.currency",
+			"day":      "$_id.day",
+			"amount":   1,
+		}},
+	}
+}
+
+// expenseTotals returns the totals of all entries matching the filter, each
+// day's sums converted at the rate of that day.
+func (s *ExpenseService) expenseTotals(ctx context.Context, filter map[string]interface{}, baseCurrency string) (int64, int64, map[string]int64, bool, error) {
+	result, err := s.mongoDBRepo.Aggregate(ctx, expenseTotalsPipeline(filter))
+	if !result.Success {
+		return 0, 0, nil, false, err
+	}
+	sums := make([]map[string]interface{}, 0, len(result.Data))
+	complete := true
+	for _, document := range result.Data {
+		day, _ := document["day"].(string)
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			complete = false
+			continue
+		}
+		document["date"] = date
+		sums = append(sums, document)
+	}
+	total, income, totals, converted := s.convertExpenses(sums, baseCurrency)
+	return total, income, totals, complete && converted, nil
 }
 
 func (s *ExpenseService) HandleGetExpense(data []byte, replyTo string, correlationId string) {
@@ -124,15 +412,23 @@ func (s *ExpenseService) HandleGetExpense(data []byte, replyTo string, correlati
         return
     }
 	
-	filter := map[string]interface{}{
-		"userId": getExpenseServiceRequestData.UserId,
-	}
-	for key, value := range getExpenseServiceRequestData.Filter {
-		filter[key] = value
+	filter, err := s.expenseQueryFilter(context.Background(), getExpenseServiceRequestData)
+	if entryErr, ok := err.(*entryError); ok {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetExpenseResponse",
+			getExpenseServiceResponse{
+				Message: entryErr.message,
+				Success: false,
+				Status: entryErr.status,
+			},
+		)
+		return
 	}
-	result, err := s.mongoDBRepo.Find(context.Background(), filter)
-	if !result.Success {
-        log.Println(err)
+	if err != nil {
+		log.Println(err)
 		SendResponse(
 			s.channel,
 			replyTo,
@@ -144,33 +440,165 @@ func (s *ExpenseService) HandleGetExpense(data []byte, replyTo string, correlati
 				Expenses: nil,
 			},
 		)
-        return
+		return
 	}
-	if len(result.Data) == 0 {
+	sortField := getExpenseServiceRequestData.SortField
+	direction := getExpenseServiceRequestData.SortDirection
+	if direction == 0 {
+		direction = 1
+	}
+	if getExpenseServiceRequestData.Query != "" {
+		// Search results come best match first.
+		sortField, direction = "score", -1
+	}
+	var after map[string]interface{}
+	if getExpenseServiceRequestData.Cursor != "" {
+		cursor, err := decodeCursor(getExpenseServiceRequestData.Cursor)
+		if err != nil || cursor.Field != sortField || cursor.Direction != direction {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"GetExpenseResponse",
+				getExpenseServiceResponse{
+					Message: "Cursor is invalid!",
+					Success: false,
+					Status: http.StatusBadRequest,
+				},
+			)
+			return
+		}
+		after = cursorCondition(cursor)
+	}
+	projection := expenseProjection(getExpenseServiceRequestData.Fields, sortField)
+
+	var result *repositories.GenericResponse
+	if getExpenseServiceRequestData.Query != "" {
+		// The score only exists once the text search ran, so the cursor is matched after it.
+		pipeline := []interface{}{
+			map[string]interface{}{"$match": filter},
+			map[string]interface{}{"$addFields": map[string]interface{}{"score": map[string]interface{}{"$meta": "textScore"}}},
+		}
+		if after != nil {
+			pipeline = append(pipeline, map[string]interface{}{"$match": after})
+		}
+		pipeline = append(pipeline, map[string]interface{}{"$sort": bson.D{{Key: "score", Value: -1}, {Key: "expenseId", Value: -1}}})
+		if getExpenseServiceRequestData.Limit > 0 {
+			pipeline = append(pipeline, map[string]interface{}{"$limit": getExpenseServiceRequestData.Limit + 1})
+		}
+		pipeline = append(pipeline, map[string]interface{}{"$project": projection})
+		result, err = s.mongoDBRepo.Aggregate(context.Background(), pipeline)
+	} else {
+		// The totals still cover the entries of the earlier pages.
+		pageFilter := filter
+		if after != nil {
+			pageFilter = make(map[string]interface{}, len(filter)+1)
+			for key, value := range filter {
+				pageFilter[key] = value
+			}
+			pageFilter["$and"] = []interface{}{after}
+		}
+		findOptions := options.Find().SetProjection(projection)
+		if sortField != "" {
+			// The expense id breaks ties, so pages neither skip nor repeat entries.
+			findOptions.SetSort(bson.D{{Key: sortField, Value: direction}, {Key: "expenseId", Value: direction}})
+		}
+		if getExpenseServiceRequestData.Limit > 0 {
+			// One more entry tells whether there is a next page.
+			findOptions.SetLimit(int64(getExpenseServiceRequestData.Limit + 1))
+		}
+		result, err = s.mongoDBRepo.Find(context.Background(), pageFilter, findOptions)
+	}
+	if !result.Success {
+        log.Println(err)
 		SendResponse(
 			s.channel,
 			replyTo,
 			correlationId,
 			"GetExpenseResponse",
 			getExpenseServiceResponse{
-				Message: "No expenses found!",
-				Success: true,
-				Expenses: []map[string]interface{}{},
+				Message: "An error occured!",
+				Success: false,
+				Expenses: nil,
 			},
 		)
-		return
+        return
+	}
+	baseCurrency := getExpenseServiceRequestData.BaseCurrency
+	if !money.IsCurrency(baseCurrency) {
+		baseCurrency = money.DefaultCurrency()
 	}
 
 	expenses := result.Data
+	nextCursor := ""
+	if getExpenseServiceRequestData.Limit > 0 && len(expenses) > getExpenseServiceRequestData.Limit {
+		expenses = expenses[:getExpenseServiceRequestData.Limit]
+		last := expenses[len(expenses)-1]
+		expenseId, _ := last["expenseId"].(string)
+		nextCursor, err = encodeCursor(expenseCursor{
+			Field:     sortField,
+			Direction: direction,
+			Value:     last[sortField],
+			ExpenseId: expenseId,
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	if getExpenseServiceRequestData.Query != "" {
+		for _, expense := range expenses {
+			delete(expense, "score")
+		}
+	}
+
+	total, income, totals, complete := s.convertExpenses(expenses, baseCurrency)
+	if getExpenseServiceRequestData.Limit > 0 {
+		total, income, totals, complete, err = s.expenseTotals(context.Background(), filter, baseCurrency)
+		if err != nil {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"GetExpenseResponse",
+				getExpenseServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+					Expenses: nil,
+				},
+			)
+			return
+		}
+	}
+	for _, expense := range expenses {
+		formatAmount(expense, baseCurrency)
+		selectFields(expense, getExpenseServiceRequestData.Fields)
+	}
+	formattedTotals := make(map[string]string)
+	for currency, amount := range totals {
+		formattedTotals[currency] = money.Format(amount, currency)
+	}
+
+	message := "Operation is successful!"
+	if len(expenses) == 0 {
+		message = "No expenses found!"
+	}
 	SendResponse(
 		s.channel, 
 		replyTo, 
 		correlationId, 
 		"GetExpenseResponse", 
 		getExpenseServiceResponse{
-			Message: "Operation is successful!",
+			Message: message,
 			Success: true,
 			Expenses: expenses,
+			BaseCurrency: baseCurrency,
+			Total: money.Format(total, baseCurrency),
+			Income: money.Format(income, baseCurrency),
+			Net: money.Format(income-total, baseCurrency),
+			Totals: formattedTotals,
+			MissingRates: !complete,
+			NextCursor: nextCursor,
 		},
 	)
 }
@@ -179,13 +607,123 @@ type addExpenseServiceRequest struct {
 	UserId		string 	`json:"userId"`
 	Action   	string 	`json:"action"`
 	Description string 	`json:"description"`
-	Amount 		float32 `json:"amount"`
+	Payee		string	`json:"payee"`
+	Notes		string	`json:"notes"`
+	Type		string	`json:"type"`
+	Amount 		json.Number `json:"amount"`
+	Currency	string	`json:"currency"`
 	Category 	string 	`json:"category"`
+	AccountId	string	`json:"accountId"`
+	ToAccountId	string	`json:"toAccountId"`
+	Tags		[]string `json:"tags"`
+	Date		time.Time `json:"date"`
 }
 
 type addExpenseServiceResponse struct {
 	Message string 		   `json:"message"`
 	Success bool 		   `json:"success"`
+	Status	int			   `json:"status,omitempty"`
+	Anomaly	*models.Anomaly `json:"anomaly,omitempty"`
+}
+
+// newExpense validates a new entry and builds it as it is stored: the user's
+// rules fill in a missing category and tags, and expenses are checked for
+// anomalies. New entries and imports share it.
+func (s *ExpenseService) newExpense(ctx context.Context, addExpenseServiceRequestData addExpenseServiceRequest) (models.Expense, error) {
+	amount, err := money.Parse(addExpenseServiceRequestData.Amount.String(), addExpenseServiceRequestData.Currency)
+	if err != nil {
+		return models.Expense{}, &entryError{status: http.StatusBadRequest, message: err.Error()}
+	}
+
+	err = s.validateEntryAccounts(ctx, addExpenseServiceRequestData.UserId, addExpenseServiceRequestData.AccountId, addExpenseServiceRequestData.ToAccountId)
+	if err == errUnknownAccount {
+		return models.Expense{}, &entryError{status: http.StatusBadRequest, message: "Account is unknown!"}
+	}
+	if err != nil {
+		return models.Expense{}, err
+	}
+
+	entry := rules.Entry{
+		Payee:       addExpenseServiceRequestData.Payee,
+		Description: addExpenseServiceRequestData.Description,
+		Amount:      amount,
+		Currency:    addExpenseServiceRequestData.Currency,
+	}
+	addExpenseServiceRequestData.Category, addExpenseServiceRequestData.Tags, err = s.fillFromRules(ctx, addExpenseServiceRequestData.UserId, entry, addExpenseServiceRequestData.Category, addExpenseServiceRequestData.Tags)
+	if err != nil {
+		return models.Expense{}, err
+	}
+	if entryTypeOrDefault(addExpenseServiceRequestData.Type) == "expense" && addExpenseServiceRequestData.Category == "" {
+		return models.Expense{}, &entryError{status: http.StatusBadRequest, message: "\"Category\" is required for expenses and no rule matched!"}
+	}
+
+	category, err := s.resolveEntryCategory(ctx, addExpenseServiceRequestData.UserId, addExpenseServiceRequestData.Category)
+	if err == errUnknownCategory {
+		return models.Expense{}, &entryError{status: http.StatusBadRequest, message: "Category is unknown!"}
+	}
+	if err != nil {
+		return models.Expense{}, err
+	}
+
+	expenseId := uuid.New().String()
+	now := time.Now().UTC()
+
+	date := addExpenseServiceRequestData.Date
+	if date.IsZero() {
+		date = now
+	}
+
+	tags := addExpenseServiceRequestData.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	// A failed check must not keep the expense from being recorded.
+	var expenseAnomaly *models.Anomaly
+	if entryTypeOrDefault(addExpenseServiceRequestData.Type) == "expense" {
+		expenseAnomaly, err = s.detectAnomaly(ctx, addExpenseServiceRequestData.UserId, category, amount, addExpenseServiceRequestData.Currency, date)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	expense := models.Expense{
+		ExpenseId:   expenseId,
+		UserId:      addExpenseServiceRequestData.UserId,
+		Description: addExpenseServiceRequestData.Description,
+		Payee:       addExpenseServiceRequestData.Payee,
+		Notes:       addExpenseServiceRequestData.Notes,
+		Type:        entryTypeOrDefault(addExpenseServiceRequestData.Type),
+		Amount:      amount,
+		Currency:    addExpenseServiceRequestData.Currency,
+		Category:    category,
+		AccountId:   addExpenseServiceRequestData.AccountId,
+		ToAccountId: addExpenseServiceRequestData.ToAccountId,
+		Tags:        tags,
+		Date:        date,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Anomaly:     expenseAnomaly,
+		Version:     1,
+	}
+	return expense, nil
+}
+
+func (s *ExpenseService) addExpense(ctx context.Context, addExpenseServiceRequestData addExpenseServiceRequest) (models.Expense, error) {
+	expense, err := s.newExpense(ctx, addExpenseServiceRequestData)
+	if err != nil {
+		return models.Expense{}, err
+	}
+
+	result, err := s.mongoDBRepo.Insert(ctx, expense)
+	if !result.Success {
+		return models.Expense{}, err
+	}
+	err = s.recordHistory(ctx, newExpenseHistory(expense.UserId, expense.ExpenseId, expense.UserId, "create", nil, expense))
+	if err != nil {
+		return models.Expense{}, err
+	}
+	return expense, nil
 }
 
 func (s *ExpenseService) HandleAddExpense(data []byte, replyTo string, correlationId string) {
@@ -205,20 +743,24 @@ func (s *ExpenseService) HandleAddExpense(data []byte, replyTo string, correlati
 		)
         return
     }
-	
-	expenseId := uuid.New().String()
 
-	expense := models.Expense{
-		ExpenseId:   expenseId,
-		UserId:      addExpenseServiceRequestData.UserId,
-		Description: addExpenseServiceRequestData.Description,
-		Amount:      addExpenseServiceRequestData.Amount,
-		Category:    addExpenseServiceRequestData.Category,
+	expense, err := s.addExpense(context.Background(), addExpenseServiceRequestData)
+	if entryErr, ok := err.(*entryError); ok {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddExpenseResponse",
+			addExpenseServiceResponse{
+				Message: entryErr.message,
+				Success: false,
+				Status: entryErr.status,
+			},
+		)
+		return
 	}
-
-	result, err := s.mongoDBRepo.Insert(context.Background(), expense)
-	if !result.Success {
-        log.Println(err)
+	if err != nil {
+		log.Println(err)
 		SendResponse(
 			s.channel,
 			replyTo,
@@ -229,7 +771,7 @@ func (s *ExpenseService) HandleAddExpense(data []byte, replyTo string, correlati
 				Success: false,
 			},
 		)
-        return
+		return
 	}
 
 	SendResponse(
@@ -240,6 +782,7 @@ func (s *ExpenseService) HandleAddExpense(data []byte, replyTo string, correlati
 		addExpenseServiceResponse{
 			Message: "Operation is successful!",
 			Success: true,
+			Anomaly: expense.Anomaly,
 		},
 	)
 }
@@ -249,39 +792,137 @@ type updateExpenseServiceRequest struct {
 	UserId		string 	`json:"userId"`
 	ExpenseId	string 	`json:"expenseId"`
 	Description string 	`json:"description"`
-	Amount 		float32 `json:"amount"`
+	Payee		string	`json:"payee"`
+	Notes		string	`json:"notes"`
+	Type		string	`json:"type"`
+	Amount 		json.Number `json:"amount"`
+	Currency	string	`json:"currency"`
 	Category 	string 	`json:"category"`
+	AccountId	string	`json:"accountId"`
+	ToAccountId	string	`json:"toAccountId"`
+	Tags		[]string `json:"tags"`
+	Date		time.Time `json:"date"`
+	Version		*int64	`json:"version,omitempty"`
 }
 
 type updateExpenseServiceResponse struct {
 	Message string 		   `json:"message"`
 	Success bool 		   `json:"success"`
+	Status	int			   `json:"status,omitempty"`
+	Version	int64		   `json:"version,omitempty"`
 }
 
-func (s *ExpenseService) HandleUpdateExpense(data []byte, replyTo string, correlationId string) {
-	updateExpenseServiceRequestData := updateExpenseServiceRequest{}
-	err := json.Unmarshal(data, &updateExpenseServiceRequestData)
-    if err != nil {
-        log.Println(err)
-		SendResponse(
-			s.channel,
-			replyTo,
-			correlationId,
-			"UpdateExpenseResponse",
-			updateExpenseServiceResponse{
-				Message: "An error occured!",
-				Success: false,
-			},
-		)
-        return
-    }
-	
+// updateExpense replaces an entry of the user and returns its new version. The
+// write only applies to the version it was based on; without a version from
+// the client, that is the version read here.
+func (s *ExpenseService) updateExpense(ctx context.Context, updateExpenseServiceRequestData updateExpenseServiceRequest) (int64, error) {
 	filter := map[string]interface{}{
 		"userId": updateExpenseServiceRequestData.UserId,
 		"expenseId": updateExpenseServiceRequestData.ExpenseId,
+		"deletedAt": notDeleted(),
 	}
-	result, err := s.mongoDBRepo.Find(context.Background(), filter)
+	result, err := s.mongoDBRepo.Find(ctx, filter)
 	if !result.Success {
+		return 0, err
+	}
+	if len(result.Data) == 0 {
+		return 0, &entryError{status: http.StatusNotFound, message: "Expense not found!"}
+	}
+
+	before := result.Data[0]
+	version := documentVersion(before)
+	if updateExpenseServiceRequestData.Version != nil && *updateExpenseServiceRequestData.Version != version {
+		return 0, errStaleVersion
+	}
+	// Float amounts of entries that were not migrated yet are read as minor units first.
+	storedAmount, storedCurrency := documentAmount(before)
+	expense := models.Expense{}
+	if err := decodeDocument(patchedDocument(before, map[string]interface{}{"amount": storedAmount, "currency": storedCurrency}), &expense); err != nil {
+		return 0, err
+	}
+
+	amount, err := money.Parse(updateExpenseServiceRequestData.Amount.String(), updateExpenseServiceRequestData.Currency)
+	if err != nil {
+		return 0, &entryError{status: http.StatusBadRequest, message: err.Error()}
+	}
+
+	err = s.validateEntryAccounts(ctx, updateExpenseServiceRequestData.UserId, updateExpenseServiceRequestData.AccountId, updateExpenseServiceRequestData.ToAccountId)
+	if err == errUnknownAccount {
+		return 0, &entryError{status: http.StatusBadRequest, message: "Account is unknown!"}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	category, err := s.resolveEntryCategory(ctx, updateExpenseServiceRequestData.UserId, updateExpenseServiceRequestData.Category)
+	if err == errUnknownCategory {
+		return 0, &entryError{status: http.StatusBadRequest, message: "Category is unknown!"}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	previous := expense
+	expense.Description = updateExpenseServiceRequestData.Description
+	expense.Payee = updateExpenseServiceRequestData.Payee
+	expense.Notes = updateExpenseServiceRequestData.Notes
+	expense.Type = entryTypeOrDefault(updateExpenseServiceRequestData.Type)
+	expense.Amount = amount
+	expense.Currency = updateExpenseServiceRequestData.Currency
+	expense.Category = category
+	expense.AccountId = updateExpenseServiceRequestData.AccountId
+	expense.ToAccountId = updateExpenseServiceRequestData.ToAccountId
+	expense.Tags = updateExpenseServiceRequestData.Tags
+	if expense.Tags == nil {
+		expense.Tags = []string{}
+	}
+	now := time.Now().UTC()
+	// Expenses stored before timestamps were introduced have none; backfill them on first update.
+	if expense.CreatedAt.IsZero() {
+		expense.CreatedAt = now
+	}
+	if !updateExpenseServiceRequestData.Date.IsZero() {
+		expense.Date = updateExpenseServiceRequestData.Date
+	} else if expense.Date.IsZero() {
+		expense.Date = expense.CreatedAt
+	}
+	expense.UpdatedAt = now
+	expense.Version = version + 1
+
+	// The entry is checked again when what it was checked on changes, which
+	// also clears a flag that no longer applies.
+	if expense.Amount != previous.Amount || expense.Currency != previous.Currency || expense.Category != previous.Category || expense.Type != previous.Type || !expense.Date.Equal(previous.Date) {
+		expense.Anomaly = nil
+		if expense.Type == "expense" {
+			expense.Anomaly, err = s.detectAnomaly(ctx, expense.UserId, expense.Category, expense.Amount, expense.Currency, expense.Date)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	update := map[string]interface{}{"$set": expense}
+	if expense.Anomaly == nil {
+		update["$unset"] = map[string]interface{}{"anomaly": ""}
+	}
+	filter["version"] = versionFilter(version)
+	result, err = s.mongoDBRepo.UpdateIfMatch(ctx, filter, update)
+	if err == repositories.ErrNoMatch {
+		return 0, errStaleVersion
+	}
+	if !result.Success {
+		return 0, err
+	}
+	err = s.recordHistory(ctx, newExpenseHistory(expense.UserId, expense.ExpenseId, updateExpenseServiceRequestData.UserId, "update", before, expense))
+	if err != nil {
+		return 0, err
+	}
+	return expense.Version, nil
+}
+
+func (s *ExpenseService) HandleUpdateExpense(data []byte, replyTo string, correlationId string) {
+	updateExpenseServiceRequestData := updateExpenseServiceRequest{}
+	err := json.Unmarshal(data, &updateExpenseServiceRequestData)
+    if err != nil {
         log.Println(err)
 		SendResponse(
 			s.channel,
@@ -294,44 +935,25 @@ func (s *ExpenseService) HandleUpdateExpense(data []byte, replyTo string, correl
 			},
 		)
         return
-	}
-	if len(result.Data) == 0 {
+    }
+
+	version, err := s.updateExpense(context.Background(), updateExpenseServiceRequestData)
+	if entryErr, ok := err.(*entryError); ok {
 		SendResponse(
 			s.channel,
 			replyTo,
 			correlationId,
 			"UpdateExpenseResponse",
 			updateExpenseServiceResponse{
-				Message: "Expense not found!",
+				Message: entryErr.message,
 				Success: false,
+				Status: entryErr.status,
 			},
 		)
 		return
 	}
-
-	expense := models.Expense{}
-	jsonData, err := json.Marshal(result.Data[0])
-    if err != nil {
-        log.Println(err)
-		SendResponse(
-			s.channel,
-			replyTo,
-			correlationId,
-			"UpdateExpenseResponse",
-			updateExpenseServiceResponse{
-				Message: "An error occured!",
-				Success: false,
-			},
-		)
-        return
-    }
-	json.Unmarshal(jsonData, &expense)
-	expense.Description = updateExpenseServiceRequestData.Description
-	expense.Amount = updateExpenseServiceRequestData.Amount
-	expense.Category = updateExpenseServiceRequestData.Category
-	result, err = s.mongoDBRepo.Update(context.Background(), filter, expense)
-	if !result.Success {
-        log.Println(err)
+	if err != nil {
+		log.Println(err)
 		SendResponse(
 			s.channel,
 			replyTo,
@@ -342,7 +964,7 @@ func (s *ExpenseService) HandleUpdateExpense(data []byte, replyTo string, correl
 				Success: false,
 			},
 		)
-        return
+		return
 	}
 
 	SendResponse(
@@ -353,6 +975,7 @@ func (s *ExpenseService) HandleUpdateExpense(data []byte, replyTo string, correl
 		updateExpenseServiceResponse{
 			Message: "Operation is successful!",
 			Success: true,
+			Version: version,
 		},
 	)
 }
@@ -361,11 +984,52 @@ type removeExpenseServiceRequest struct {
 	Action   	string `json:"action"`
 	UserId		string `json:"userId"`
 	ExpenseId	string `json:"expenseId"`
+	Version		*int64 `json:"version,omitempty"`
 }
 
 type removeExpenseServiceResponse struct {
 	Message string 		   `json:"message"`
 	Success bool 		   `json:"success"`
+	Status	int			   `json:"status,omitempty"`
+}
+
+// removeExpense moves an entry of the user to the trash. It is deleted for
+// good, together with its receipts, once the retention has passed. As for
+// updates, a version from the client must still be the current one.
+func (s *ExpenseService) removeExpense(ctx context.Context, userId string, expenseId string, expectedVersion *int64) error {
+	filter := map[string]interface{}{
+		"userId": userId,
+		"expenseId": expenseId,
+		"deletedAt": notDeleted(),
+	}
+	result, err := s.mongoDBRepo.Find(ctx, filter)
+	if !result.Success {
+		return err
+	}
+	if len(result.Data) == 0 {
+		return &entryError{status: http.StatusNotFound, message: "Expense not found!"}
+	}
+	before := result.Data[0]
+	version := documentVersion(before)
+	if expectedVersion != nil && *expectedVersion != version {
+		return errStaleVersion
+	}
+	
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{
+			"deletedAt": time.Now().UTC(),
+			"version":   version + 1,
+		},
+	}
+	filter["version"] = versionFilter(version)
+	result, err = s.mongoDBRepo.UpdateIfMatch(ctx, filter, update)
+	if err == repositories.ErrNoMatch {
+		return errStaleVersion
+	}
+	if !result.Success {
+		return err
+	}
+	return s.recordHistory(ctx, newExpenseHistory(userId, expenseId, userId, "delete", before, nil))
 }
 
 func (s *ExpenseService) HandleRemoveExpense(data []byte, replyTo string, correlationId string) { 
@@ -385,43 +1049,24 @@ func (s *ExpenseService) HandleRemoveExpense(data []byte, replyTo string, correl
 		)
         return
     }
-	
-	filter := map[string]interface{}{
-		"userId": removeExpenseServiceRequestData.UserId,
-		"expenseId": removeExpenseServiceRequestData.ExpenseId,
-	}
-	result, err := s.mongoDBRepo.Find(context.Background(), filter)
-	if !result.Success {
-        log.Println(err)
-		SendResponse(
-			s.channel,
-			replyTo,
-			correlationId,
-			"RemoveExpenseResponse",
-			removeExpenseServiceResponse{
-				Message: "An error occured!",
-				Success: false,
-			},
-		)
-        return
-	}
-	if len(result.Data) == 0 {
+
+	err = s.removeExpense(context.Background(), removeExpenseServiceRequestData.UserId, removeExpenseServiceRequestData.ExpenseId, removeExpenseServiceRequestData.Version)
+	if entryErr, ok := err.(*entryError); ok {
 		SendResponse(
 			s.channel,
 			replyTo,
 			correlationId,
 			"RemoveExpenseResponse",
 			removeExpenseServiceResponse{
-				Message: "Expense not found!",
+				Message: entryErr.message,
 				Success: false,
+				Status: entryErr.status,
 			},
 		)
 		return
 	}
-	
-	result, err = s.mongoDBRepo.Delete(context.Background(), filter)
-	if !result.Success {
-        log.Println(err)
+	if err != nil {
+		log.Println(err)
 		SendResponse(
 			s.channel,
 			replyTo,
@@ -432,7 +1077,7 @@ func (s *ExpenseService) HandleRemoveExpense(data []byte, replyTo string, correl
 				Success: false,
 			},
 		)
-        return
+		return
 	}
 
 	SendResponse(
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense/internal/money"
+	"log"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Exports are sent in chunks so that neither side holds the whole result set.
+const exportChunkSize = 200
+
+// exportIdleTimeout bounds how long an export waits for the next chunk to be
+// asked for, e.g. after the client went away.
+const exportIdleTimeout = time.Minute
+
+var errExportAbandoned = errors.New("export is abandoned")
+
+// awaitExportRequest waits until the handler asks for the next chunk of an export.
+func awaitExportRequest(requests <-chan amqp.Delivery, correlationId string) error {
+	timeout := time.NewTimer(exportIdleTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case request, ok := <-requests:
+			if !ok {
+				return errExportAbandoned
+			}
+			if request.CorrelationId == correlationId {
+				return nil
+			}
+		case <-timeout.C:
+			return errExportAbandoned
+		}
+	}
+}
+
+type exportExpensesServiceResponse struct {
+	Message		string						`json:"message"`
+	Success		bool						`json:"success"`
+	Done		bool						`json:"done"`
+	Status		int							`json:"status,omitempty"`
+	NextTo		string						`json:"nextTo,omitempty"`
+	Expenses	[]map[string]interface{}	`json:"expenses"`
+}
+
+// HandleExportExpenses streams the expenses matching the filters of
+// GET /expense, oldest first. Every chunk is a separate reply with the same
+// correlation id; the last one is marked as done. The next chunk is only read
+// once the handler asks for it on the queue named in "nextTo", so a slow client
+// slows down the export instead of piling up chunks in the broker. It runs on
+// a channel of its own, apart from the other requests.
+func (s *ExpenseService) HandleExportExpenses(data []byte, replyTo string, correlationId string) {
+	exportExpensesServiceRequestData := getExpenseServiceRequest{}
+	err := json.Unmarshal(data, &exportExpensesServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"ExportExpensesResponse",
+			exportExpensesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+				Done: true,
+			},
+		)
+		return
+	}
+
+	channel, err := s.connection.Channel()
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"ExportExpensesResponse",
+			exportExpensesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+				Done: true,
+			},
+		)
+		return
+	}
+	defer channel.Close()
+	requestQueue, err := channel.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			channel,
+			replyTo,
+			correlationId,
+			"ExportExpensesResponse",
+			exportExpensesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+				Done: true,
+			},
+		)
+		return
+	}
+	requests, err := channel.Consume(requestQueue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			channel,
+			replyTo,
+			correlationId,
+			"ExportExpensesResponse",
+			exportExpensesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+				Done: true,
+			},
+		)
+		return
+	}
+
+	baseCurrency := exportExpensesServiceRequestData.BaseCurrency
+	if !money.IsCurrency(baseCurrency) {
+		baseCurrency = money.DefaultCurrency()
+	}
+
+	chunk := make([]map[string]interface{}, 0, exportChunkSize)
+	sendChunk := func(done bool) {
+		nextTo := ""
+		if !done {
+			nextTo = requestQueue.Name
+		}
+		s.convertExpenses(chunk, baseCurrency)
+		for _, expense := range chunk {
+			formatAmount(expense, baseCurrency)
+		}
+		SendResponse(
+			channel,
+			replyTo,
+			correlationId,
+			"ExportExpensesResponse",
+			exportExpensesServiceResponse{
+				Message: "Operation is successful!",
+				Success: true,
+				Done: done,
+				NextTo: nextTo,
+				Expenses: chunk,
+			},
+		)
+		chunk = make([]map[string]interface{}, 0, exportChunkSize)
+	}
+
+	filter, err := s.expenseQueryFilter(context.Background(), exportExpensesServiceRequestData)
+	if entryErr, ok := err.(*entryError); ok {
+		SendResponse(
+			channel,
+			replyTo,
+			correlationId,
+			"ExportExpensesResponse",
+			exportExpensesServiceResponse{
+				Message: entryErr.message,
+				Success: false,
+				Done: true,
+				Status: entryErr.status,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			channel,
+			replyTo,
+			correlationId,
+			"ExportExpensesResponse",
+			exportExpensesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+				Done: true,
+			},
+		)
+		return
+	}
+	sort := map[string]interface{}{"date": 1}
+	err = s.mongoDBRepo.FindEach(context.Background(), filter, sort, func(expense map[string]interface{}) error {
+		chunk = append(chunk, expense)
+		if len(chunk) == exportChunkSize {
+			sendChunk(false)
+			return awaitExportRequest(requests, correlationId)
+		}
+		return nil
+	})
+	if err == errExportAbandoned {
+		log.Println(err)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			channel,
+			replyTo,
+			correlationId,
+			"ExportExpensesResponse",
+			exportExpensesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+				Done: true,
+			},
+		)
+		return
+	}
+	sendChunk(true)
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/money"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	defaultForecastHistory	= 6
+	// forecastBandWidth spans roughly 80% of a normal distribution.
+	forecastBandWidth	= 1.28
+)
+
+type forecast struct {
+	forecast	int64
+	low			int64
+	high		int64
+	average		int64
+}
+
+// forecastAmount projects the total at the end of a period from the amount
+// spent so far and the prior periods. The remaining spending is a blend of the
+// current run-rate and what prior periods spent after the same point; the
+// run-rate weighs more the further the period has progressed. The band is
+// derived from the spread of the prior periods, so it is empty without them.
+func forecastAmount(spent int64, totals []int64, before []int64, elapsed float64) forecast {
+	runRate := 0.0
+	if elapsed > 0 {
+		runRate = float64(spent) / elapsed * (1 - elapsed)
+	}
+	if len(totals) == 0 {
+		projected := spent + int64(math.Round(runRate))
+		return forecast{forecast: projected, low: projected, high: projected}
+	}
+
+	mean, sum := 0.0, 0.0
+	for i := range totals {
+		mean += float64(totals[i] - before[i])
+		sum += float64(totals[i])
+	}
+	mean /= float64(len(totals))
+	deviation := 0.0
+	for i := range totals {
+		difference := float64(totals[i]-before[i]) - mean
+		deviation += difference * difference
+	}
+	deviation = math.Sqrt(deviation / float64(len(totals)))
+
+	remaining := elapsed*runRate + (1-elapsed)*mean
+	low := math.Max(remaining-forecastBandWidth*deviation, 0)
+	high := math.Max(remaining+forecastBandWidth*deviation, 0)
+	return forecast{
+		forecast: spent + int64(math.Round(remaining)),
+		low:      spent + int64(math.Round(low)),
+		high:     spent + int64(math.Round(high)),
+		average:  int64(math.Round(sum / float64(len(totals)))),
+	}
+}
+
+type forecastCategoryServiceResponse struct {
+	Category	string	`json:"category"`
+	Spent		string	`json:"spent"`
+	Forecast	string	`json:"forecast"`
+	Low			string	`json:"low"`
+	High		string	`json:"high"`
+	Average		string	`json:"average"`
+}
+
+type getForecastServiceRequest struct {
+	Action			string		`json:"action"`
+	UserId			string		`json:"userId"`
+	Date			time.Time	`json:"date"`
+	Period			string		`json:"period"`
+	History			int			`json:"history"`
+	BaseCurrency	string		`json:"baseCurrency"`
+}
+
+type getForecastServiceResponse struct {
+	Message			string								`json:"message"`
+	Success			bool								`json:"success"`
+	BaseCurrency	string								`json:"baseCurrency"`
+	PeriodStart		time.Time							`json:"periodStart"`
+	PeriodEnd		time.Time							`json:"periodEnd"`
+	Elapsed			float64								`json:"elapsed"`
+	HistoryPeriods	int									`json:"historyPeriods"`
+	Categories		[]forecastCategoryServiceResponse	`json:"categories"`
+	Spent			string								`json:"spent"`
+	Forecast		string								`json:"forecast"`
+	Low				string								`json:"low"`
+	High			string								`json:"high"`
+	MissingRates	bool								`json:"missingRates"`
+}
+
+func (s *ExpenseService) HandleGetForecast(data []byte, replyTo string, correlationId string) {
+	getForecastServiceRequestData := getForecastServiceRequest{}
+	err := json.Unmarshal(data, &getForecastServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetForecastResponse",
+			getForecastServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	// The date is the exclusive end of what has been spent so far; the period
+	// is the one it closes or falls in.
+	date := getForecastServiceRequestData.Date
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+	start, end := budgetPeriod(getForecastServiceRequestData.Period, date.Add(-time.Nanosecond))
+	elapsed := float64(date.Sub(start)) / float64(end.Sub(start))
+
+	history := getForecastServiceRequestData.History
+	if history <= 0 {
+		history = defaultForecastHistory
+	}
+	periodStarts := make([]time.Time, history)
+	periodEnds := make([]time.Time, history)
+	for i := 0; i < history; i++ {
+		previous := start
+		if i > 0 {
+			previous = periodStarts[i-1]
+		}
+		periodStarts[i], periodEnds[i] = budgetPeriod(getForecastServiceRequestData.Period, previous.Add(-time.Nanosecond))
+	}
+
+	filter := map[string]interface{}{
+		"userId":    getForecastServiceRequestData.UserId,
+		"type":      expenseTypeFilter(),
+		"deletedAt": notDeleted(),
+		"date": map[string]interface{}{
+			"$gte": periodStarts[history-1],
+			"$lt":  date,
+		},
+	}
+	result, err := s.mongoDBRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetForecastResponse",
+			getForecastServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	baseCurrency := getForecastServiceRequestData.BaseCurrency
+	if !money.IsCurrency(baseCurrency) {
+		baseCurrency = money.DefaultCurrency()
+	}
+
+	type categoryHistory struct {
+		spent	int64
+		totals	[]int64
+		before	[]int64
+	}
+	newCategoryHistory := func() *categoryHistory {
+		return &categoryHistory{totals: make([]int64, history), before: make([]int64, history)}
+	}
+	categories := make(map[string]*categoryHistory)
+	overall := newCategoryHistory()
+	first := date
+	complete := true
+	for _, expense := range result.Data {
+		amount, currency := documentAmount(expense)
+		expenseDate := expenseDate(expense)
+		converted, _, err := s.rates.Convert(amount, currency, baseCurrency, expenseDate)
+		if err != nil {
+			complete = false
+			continue
+		}
+		if expenseDate.Before(first) {
+			first = expenseDate
+		}
+
+		category, _ := expense["category"].(string)
+		categoryData, exists := categories[category]
+		if !exists {
+			categoryData = newCategoryHistory()
+			categories[category] = categoryData
+		}
+		if !expenseDate.Before(start) {
+			categoryData.spent += converted
+			overall.spent += converted
+			continue
+		}
+		for i := range periodStarts {
+			if expenseDate.Before(periodStarts[i]) || !expenseDate.Before(periodEnds[i]) {
+				continue
+			}
+			categoryData.totals[i] += converted
+			overall.totals[i] += converted
+			// Prior periods are compared at the same share of their length.
+			point := periodStarts[i].Add(time.Duration(elapsed * float64(periodEnds[i].Sub(periodStarts[i]))))
+			if expenseDate.Before(point) {
+				categoryData.before[i] += converted
+				overall.before[i] += converted
+			}
+			break
+		}
+	}
+
+	// Periods before the first expense of the user would only pull the
+	// averages down, so they are not part of the history.
+	historyPeriods := 0
+	for historyPeriods < history && periodEnds[historyPeriods].After(first) {
+		historyPeriods++
+	}
+
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	forecastCategories := make([]forecastCategoryServiceResponse, 0, len(names))
+	for _, name := range names {
+		categoryData := categories[name]
+		categoryForecast := forecastAmount(categoryData.spent, categoryData.totals[:historyPeriods], categoryData.before[:historyPeriods], elapsed)
+		forecastCategories = append(forecastCategories, forecastCategoryServiceResponse{
+			Category: name,
+			Spent:    money.Format(categoryData.spent, baseCurrency),
+			Forecast: money.Format(categoryForecast.forecast, baseCurrency),
+			Low:      money.Format(categoryForecast.low, baseCurrency),
+			High:     money.Format(categoryForecast.high, baseCurrency),
+			Average:  money.Format(categoryForecast.average, baseCurrency),
+		})
+	}
+	overallForecast := forecastAmount(overall.spent, overall.totals[:historyPeriods], overall.before[:historyPeriods], elapsed)
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetForecastResponse",
+		getForecastServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			BaseCurrency: baseCurrency,
+			PeriodStart: start,
+			PeriodEnd: end,
+			Elapsed: math.Round(elapsed*1000) / 1000,
+			HistoryPeriods: historyPeriods,
+			Categories: forecastCategories,
+			Spent: money.Format(overall.spent, baseCurrency),
+			Forecast: money.Format(overallForecast.forecast, baseCurrency),
+			Low: money.Format(overallForecast.low, baseCurrency),
+			High: money.Format(overallForecast.high, baseCurrency),
+			MissingRates: !complete,
+		},
+	)
+}
@@ -0,0 +1,1738 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense/internal/models"
+	"expense/internal/money"
+	"expense/internal/repositories"
+	"log"
+	"math/bits"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const userLookupTimeout = 5 * time.Second
+
+var errUnknownMember = errors.New("member is unknown")
+
+type GroupService struct {
+	connection			*amqp.Connection
+	channel				*amqp.Channel
+	requestChannel		*amqp.Channel
+	groupRepo			*repositories.MongoDBRepository
+	groupExpenseRepo	*repositories.MongoDBRepository
+	settlementRepo		*repositories.MongoDBRepository
+}
+
+func NewGroupService(connection *amqp.Connection, uri string, databaseName string, collectionName string) (*GroupService, error) {
+	channel, err := connection.Channel()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	// Requests to the user service wait for their replies on a channel of their own.
+	requestChannel, err := connection.Channel()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	repo, err := repositories.NewMongoDBRepository(uri, databaseName, collectionName)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &GroupService{
+		connection: 		connection,
+		channel: 			channel,
+		requestChannel: 	requestChannel,
+		groupRepo: 			repo,
+		groupExpenseRepo: 	repo.WithCollection("group_expenses"),
+		settlementRepo: 	repo.WithCollection("settlements"),
+	}, nil
+}
+
+func (s *GroupService) Start() {
+	defer s.channel.Close()
+	defer s.requestChannel.Close()
+
+	rabbitmqURI := os.Getenv("RABBITMQ_URI")
+	connection, err := amqp.Dial(rabbitmqURI)
+	if err != nil {
+		log.Println(err)
+	}
+	defer connection.Close()
+
+	channel, err := connection.Channel()
+	if err != nil {
+		log.Println(err)
+	}
+	defer channel.Close()
+
+	queue, err := channel.QueueDeclare("groupQueue", false, false, false, false, nil)
+	if err != nil {
+		log.Println(err)
+	}
+
+	messages, err := s.channel.Consume(queue.Name, "", true, false, false, false, nil)
+	if err != nil {
+		log.Println(err)
+	}
+
+	for message := range messages {
+		action, ok := message.Headers["action"].(string)
+		if !ok {
+			continue
+		}
+		switch action {
+			case "GetGroup":
+				s.HandleGetGroup(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AddGroup":
+				s.HandleAddGroup(message.Body, message.ReplyTo, message.CorrelationId)
+			case "UpdateGroup":
+				s.HandleUpdateGroup(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RemoveGroup":
+				s.HandleRemoveGroup(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetGroupExpense":
+				s.HandleGetGroupExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AddGroupExpense":
+				s.HandleAddGroupExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "RemoveGroupExpense":
+				s.HandleRemoveGroupExpense(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetGroupBalance":
+				s.HandleGetGroupBalance(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetSettlement":
+				s.HandleGetSettlement(message.Body, message.ReplyTo, message.CorrelationId)
+			case "AddSettlement":
+				s.HandleAddSettlement(message.Body, message.ReplyTo, message.CorrelationId)
+			default:
+				log.Printf("Action (%s) is unknown!", action)
+		}
+	}
+}
+
+func (s *GroupService) Stop() {
+	log.Println("Group service is being stopping.")
+	if err := s.channel.Close(); err != nil {
+		log.Println(err)
+	}
+	log.Println("Group service is stopped.")
+}
+
+func uniqueIds(ids []string) []string {
+	unique := make([]string, 0, len(ids))
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+// lookupMembers asks the user service for the given users and fails with
+// errUnknownMember when any of them does not exist.
+func (s *GroupService) lookupMembers(userIds []string) ([]models.GroupMember, error) {
+	data, err := SendRequest(s.requestChannel, "userQueue", "GetUsers", map[string]interface{}{"userIds": userIds}, userLookupTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if success, _ := data["success"].(bool); !success {
+		return nil, errors.New("users could not be retrieved")
+	}
+
+	names := make(map[string]string)
+	users, _ := data["users"].([]interface{})
+	for _, eachData := range users {
+		user, _ := eachData.(map[string]interface{})
+		userId, _ := user["userId"].(string)
+		name, _ := user["name"].(string)
+		names[userId] = name
+	}
+
+	members := make([]models.GroupMember, 0, len(userIds))
+	for _, userId := range userIds {
+		name, exists := names[userId]
+		if !exists {
+			return nil, errUnknownMember
+		}
+		members = append(members, models.GroupMember{
+			UserId: userId,
+			Name:   name,
+		})
+	}
+	return members, nil
+}
+
+// findGroup returns the group only if the user is one of its members.
+func (s *GroupService) findGroup(ctx context.Context, groupId string, userId string) (*models.Group, error) {
+	filter := map[string]interface{}{
+		"groupId":        groupId,
+		"members.userId": userId,
+	}
+	result, err := s.groupRepo.Find(ctx, filter)
+	if !result.Success {
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		return nil, nil
+	}
+	group := models.Group{}
+	if err := decodeDocument(result.Data[0], &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func isGroupMember(group *models.Group, userId string) bool {
+	for _, member := range group.Members {
+		if member.UserId == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// splitGroupExpense computes the amount owed by each member. Equal and share
+// based splits hand out the remaining minor units one by one, so the amounts
+// always add up to the total.
+func splitGroupExpense(amount int64, splitType string, splits []models.GroupSplit) ([]models.GroupSplit, error) {
+	if len(splits) == 0 {
+		return nil, errors.New("\"Splits\" must contain at least one member!")
+	}
+	result := make([]models.GroupSplit, len(splits))
+	copy(result, splits)
+
+	switch splitType {
+	case "equal":
+		count := int64(len(result))
+		for i := range result {
+			result[i].Shares = 0
+			result[i].Amount = amount / count
+			if int64(i) < amount%count {
+				result[i].Amount++
+			}
+		}
+	case "shares":
+		totalShares := uint64(0)
+		for _, split := range result {
+			if split.Shares <= 0 {
+				return nil, errors.New("\"Shares\" must be positive!")
+			}
+			totalShares += uint64(split.Shares)
+		}
+		remainders := make([]uint64, len(result))
+		distributed := int64(0)
+		for i, split := range result {
+			// amount * shares / totalShares without overflowing int64.
+			hi, lo := bits.Mul64(uint64(amount), uint64(split.Shares))
+			quotient, remainder := bits.Div64(hi, lo, totalShares)
+			result[i].Amount = int64(quotient)
+			remainders[i] = remainder
+			distributed += int64(quotient)
+		}
+		order := make([]int, len(result))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return remainders[order[a]] > remainders[order[b]]
+		})
+		for i := int64(0); i < amount-distributed; i++ {
+			result[order[i]].Amount++
+		}
+	case "exact":
+		total := int64(0)
+		for i, split := range result {
+			if split.Amount < 0 {
+				return nil, errors.New("\"Amount\" of a split must not be negative!")
+			}
+			result[i].Shares = 0
+			total += split.Amount
+		}
+		if total != amount {
+			return nil, errors.New("The split amounts must add up to the expense amount!")
+		}
+	default:
+		return nil, errors.New("\"SplitType\" must be \"equal\", \"shares\" or \"exact\"!")
+	}
+	return result, nil
+}
+
+// groupBalances returns the net balance of every member: positive balances are
+// owed to the member, negative ones are owed by the member.
+func (s *GroupService) groupBalances(ctx context.Context, group *models.Group) (map[string]int64, error) {
+	balances := make(map[string]int64)
+	for _, member := range group.Members {
+		balances[member.UserId] = 0
+	}
+
+	filter := map[string]interface{}{"groupId": group.GroupId}
+	result, err := s.groupExpenseRepo.Find(ctx, filter)
+	if !result.Success {
+		return nil, err
+	}
+	for _, document := range result.Data {
+		expense := models.GroupExpense{}
+		if err := decodeDocument(document, &expense); err != nil {
+			return nil, err
+		}
+		balances[expense.PaidBy] += expense.Amount
+		for _, split := range expense.Splits {
+			balances[split.UserId] -= split.Amount
+		}
+	}
+
+	result, err = s.settlementRepo.Find(ctx, filter)
+	if !result.Success {
+		return nil, err
+	}
+	for _, document := range result.Data {
+		settlement := models.Settlement{}
+		if err := decodeDocument(document, &settlement); err != nil {
+			return nil, err
+		}
+		balances[settlement.FromUserId] += settlement.Amount
+		balances[settlement.ToUserId] -= settlement.Amount
+	}
+	return balances, nil
+}
+
+type groupDebt struct {
+	FromUserId	string
+	ToUserId	string
+	Amount		int64
+}
+
+// settleUp pairs the largest debtor with the largest creditor until every
+// balance is zero, which keeps the number of payments small.
+func settleUp(balances map[string]int64) []groupDebt {
+	type balance struct {
+		userId	string
+		amount	int64
+	}
+	debtors := make([]balance, 0)
+	creditors := make([]balance, 0)
+	for userId, amount := range balances {
+		if amount < 0 {
+			debtors = append(debtors, balance{userId, -amount})
+		} else if amount > 0 {
+			creditors = append(creditors, balance{userId, amount})
+		}
+	}
+	byAmount := func(balances []balance) func(int, int) bool {
+		return func(a, b int) bool {
+			if balances[a].amount != balances[b].amount {
+				return balances[a].amount > balances[b].amount
+			}
+			return balances[a].userId < balances[b].userId
+		}
+	}
+	sort.Slice(debtors, byAmount(debtors))
+	sort.Slice(creditors, byAmount(creditors))
+
+	debts := make([]groupDebt, 0)
+	for i, j := 0, 0; i < len(debtors) && j < len(creditors); {
+		amount := debtors[i].amount
+		if creditors[j].amount < amount {
+			amount = creditors[j].amount
+		}
+		debts = append(debts, groupDebt{
+			FromUserId: debtors[i].userId,
+			ToUserId:   creditors[j].userId,
+			Amount:     amount,
+		})
+		debtors[i].amount -= amount
+		creditors[j].amount -= amount
+		if debtors[i].amount == 0 {
+			i++
+		}
+		if creditors[j].amount == 0 {
+			j++
+		}
+	}
+	return debts
+}
+
+func formatGroupExpense(expense map[string]interface{}) {
+	_, currency := documentAmount(expense)
+	formatAmount(expense, "")
+	splits, _ := expense["splits"].(primitive.A)
+	for _, eachSplit := range splits {
+		split, ok := eachSplit.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		amount, _ := split["amount"].(int64)
+		split["amount"] = money.Format(amount, currency)
+	}
+}
+
+type getGroupServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type getGroupServiceResponse struct {
+	Message	string						`json:"message"`
+	Success	bool						`json:"success"`
+	Groups	[]map[string]interface{}	`json:"groups"`
+}
+
+func (s *GroupService) HandleGetGroup(data []byte, replyTo string, correlationId string) {
+	getGroupServiceRequestData := getGroupServiceRequest{}
+	err := json.Unmarshal(data, &getGroupServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupResponse",
+			getGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"members.userId": getGroupServiceRequestData.UserId,
+	}
+	if getGroupServiceRequestData.GroupId != "" {
+		filter["groupId"] = getGroupServiceRequestData.GroupId
+	}
+	result, err := s.groupRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupResponse",
+			getGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetGroupResponse",
+		getGroupServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Groups: result.Data,
+		},
+	)
+}
+
+type addGroupServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	Name		string		`json:"name"`
+	Currency	string		`json:"currency"`
+	MemberIds	[]string	`json:"memberIds"`
+}
+
+type addGroupServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+	GroupId	string	`json:"groupId"`
+}
+
+func (s *GroupService) HandleAddGroup(data []byte, replyTo string, correlationId string) {
+	addGroupServiceRequestData := addGroupServiceRequest{}
+	err := json.Unmarshal(data, &addGroupServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupResponse",
+			addGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	// The creator is always a member of the group.
+	memberIds := uniqueIds(append([]string{addGroupServiceRequestData.UserId}, addGroupServiceRequestData.MemberIds...))
+	members, err := s.lookupMembers(memberIds)
+	if err == errUnknownMember {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupResponse",
+			addGroupServiceResponse{
+				Message: "Member is unknown!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupResponse",
+			addGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	now := time.Now().UTC()
+	group := models.Group{
+		GroupId:   uuid.New().String(),
+		Name:      addGroupServiceRequestData.Name,
+		OwnerId:   addGroupServiceRequestData.UserId,
+		Currency:  addGroupServiceRequestData.Currency,
+		Members:   members,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	result, err := s.groupRepo.Insert(context.Background(), group)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupResponse",
+			addGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AddGroupResponse",
+		addGroupServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			GroupId: group.GroupId,
+		},
+	)
+}
+
+type updateGroupServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	GroupId		string		`json:"groupId"`
+	Name		string		`json:"name"`
+	MemberIds	[]string	`json:"memberIds"`
+}
+
+type updateGroupServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *GroupService) HandleUpdateGroup(data []byte, replyTo string, correlationId string) {
+	updateGroupServiceRequestData := updateGroupServiceRequest{}
+	err := json.Unmarshal(data, &updateGroupServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateGroupResponse",
+			updateGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	group, err := s.findGroup(context.Background(), updateGroupServiceRequestData.GroupId, updateGroupServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateGroupResponse",
+			updateGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if group == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateGroupResponse",
+			updateGroupServiceResponse{
+				Message: "Group not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+	if group.OwnerId != updateGroupServiceRequestData.UserId {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateGroupResponse",
+			updateGroupServiceResponse{
+				Message: "Only the owner can update the group!",
+				Success: false,
+				Status: http.StatusForbidden,
+			},
+		)
+		return
+	}
+
+	// The owner cannot be removed from the group.
+	memberIds := uniqueIds(append([]string{group.OwnerId}, updateGroupServiceRequestData.MemberIds...))
+	kept := make(map[string]bool)
+	for _, memberId := range memberIds {
+		kept[memberId] = true
+	}
+
+	balances, err := s.groupBalances(context.Background(), group)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateGroupResponse",
+			updateGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	for _, member := range group.Members {
+		if !kept[member.UserId] && balances[member.UserId] != 0 {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"UpdateGroupResponse",
+				updateGroupServiceResponse{
+					Message: "Members with unsettled balances cannot be removed!",
+					Success: false,
+					Status: http.StatusConflict,
+				},
+			)
+			return
+		}
+	}
+
+	members, err := s.lookupMembers(memberIds)
+	if err == errUnknownMember {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateGroupResponse",
+			updateGroupServiceResponse{
+				Message: "Member is unknown!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateGroupResponse",
+			updateGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"groupId": group.GroupId,
+	}
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{
+			"name":      updateGroupServiceRequestData.Name,
+			"members":   members,
+			"updatedAt": time.Now().UTC(),
+		},
+	}
+	result, err := s.groupRepo.Update(context.Background(), filter, update)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateGroupResponse",
+			updateGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"UpdateGroupResponse",
+		updateGroupServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type removeGroupServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type removeGroupServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *GroupService) HandleRemoveGroup(data []byte, replyTo string, correlationId string) {
+	removeGroupServiceRequestData := removeGroupServiceRequest{}
+	err := json.Unmarshal(data, &removeGroupServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupResponse",
+			removeGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	group, err := s.findGroup(context.Background(), removeGroupServiceRequestData.GroupId, removeGroupServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupResponse",
+			removeGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if group == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupResponse",
+			removeGroupServiceResponse{
+				Message: "Group not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+	if group.OwnerId != removeGroupServiceRequestData.UserId {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupResponse",
+			removeGroupServiceResponse{
+				Message: "Only the owner can remove the group!",
+				Success: false,
+				Status: http.StatusForbidden,
+			},
+		)
+		return
+	}
+
+	balances, err := s.groupBalances(context.Background(), group)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupResponse",
+			removeGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	for _, balance := range balances {
+		if balance != 0 {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"RemoveGroupResponse",
+				removeGroupServiceResponse{
+					Message: "Group has unsettled balances!",
+					Success: false,
+					Status: http.StatusConflict,
+				},
+			)
+			return
+		}
+	}
+
+	filter := map[string]interface{}{
+		"groupId": group.GroupId,
+	}
+	result, err := s.groupExpenseRepo.DeleteMany(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupResponse",
+			removeGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	result, err = s.settlementRepo.DeleteMany(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupResponse",
+			removeGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	result, err = s.groupRepo.Delete(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupResponse",
+			removeGroupServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RemoveGroupResponse",
+		removeGroupServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type getGroupExpenseServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type getGroupExpenseServiceResponse struct {
+	Message			string						`json:"message"`
+	Success			bool						`json:"success"`
+	Status			int							`json:"status,omitempty"`
+	GroupExpenses	[]map[string]interface{}	`json:"groupExpenses"`
+}
+
+func (s *GroupService) HandleGetGroupExpense(data []byte, replyTo string, correlationId string) {
+	getGroupExpenseServiceRequestData := getGroupExpenseServiceRequest{}
+	err := json.Unmarshal(data, &getGroupExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupExpenseResponse",
+			getGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	group, err := s.findGroup(context.Background(), getGroupExpenseServiceRequestData.GroupId, getGroupExpenseServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupExpenseResponse",
+			getGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if group == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupExpenseResponse",
+			getGroupExpenseServiceResponse{
+				Message: "Group not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"groupId": group.GroupId,
+	}
+	result, err := s.groupExpenseRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupExpenseResponse",
+			getGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	groupExpenses := result.Data
+	for _, groupExpense := range groupExpenses {
+		formatGroupExpense(groupExpense)
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetGroupExpenseResponse",
+		getGroupExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			GroupExpenses: groupExpenses,
+		},
+	)
+}
+
+type groupSplitServiceRequest struct {
+	UserId	string		`json:"userId"`
+	Shares	int64		`json:"shares"`
+	Amount	json.Number	`json:"amount"`
+}
+
+type addGroupExpenseServiceRequest struct {
+	Action		string						`json:"action"`
+	UserId		string						`json:"userId"`
+	GroupId		string						`json:"groupId"`
+	Description	string						`json:"description"`
+	Amount		json.Number					`json:"amount"`
+	Currency	string						`json:"currency"`
+	PaidBy		string						`json:"paidBy"`
+	SplitType	string						`json:"splitType"`
+	Splits		[]groupSplitServiceRequest	`json:"splits"`
+	Date		time.Time					`json:"date"`
+}
+
+type addGroupExpenseServiceResponse struct {
+	Message			string	`json:"message"`
+	Success			bool	`json:"success"`
+	Status			int		`json:"status,omitempty"`
+	GroupExpenseId	string	`json:"groupExpenseId"`
+}
+
+func (s *GroupService) HandleAddGroupExpense(data []byte, replyTo string, correlationId string) {
+	addGroupExpenseServiceRequestData := addGroupExpenseServiceRequest{}
+	err := json.Unmarshal(data, &addGroupExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupExpenseResponse",
+			addGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	group, err := s.findGroup(context.Background(), addGroupExpenseServiceRequestData.GroupId, addGroupExpenseServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupExpenseResponse",
+			addGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if group == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupExpenseResponse",
+			addGroupExpenseServiceResponse{
+				Message: "Group not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	// Group expenses are kept in the group currency so that balances can be netted.
+	if addGroupExpenseServiceRequestData.Currency != "" && addGroupExpenseServiceRequestData.Currency != group.Currency {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupExpenseResponse",
+			addGroupExpenseServiceResponse{
+				Message: "Currency must match the group currency!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	amount, err := money.Parse(addGroupExpenseServiceRequestData.Amount.String(), group.Currency)
+	if err != nil || amount <= 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupExpenseResponse",
+			addGroupExpenseServiceResponse{
+				Message: "\"Amount\" must be a positive amount in the group currency!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+
+	paidBy := addGroupExpenseServiceRequestData.PaidBy
+	if paidBy == "" {
+		paidBy = addGroupExpenseServiceRequestData.UserId
+	}
+	if !isGroupMember(group, paidBy) {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupExpenseResponse",
+			addGroupExpenseServiceResponse{
+				Message: "\"PaidBy\" must be a member of the group!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+
+	// Without explicit splits, an equal split covers every member.
+	splits := make([]models.GroupSplit, 0, len(addGroupExpenseServiceRequestData.Splits))
+	if len(addGroupExpenseServiceRequestData.Splits) == 0 && addGroupExpenseServiceRequestData.SplitType == "equal" {
+		for _, member := range group.Members {
+			splits = append(splits, models.GroupSplit{UserId: member.UserId})
+		}
+	}
+	seen := make(map[string]bool)
+	for _, eachSplit := range addGroupExpenseServiceRequestData.Splits {
+		if !isGroupMember(group, eachSplit.UserId) || seen[eachSplit.UserId] {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"AddGroupExpenseResponse",
+				addGroupExpenseServiceResponse{
+					Message: "Each split must name a different member of the group!",
+					Success: false,
+					Status: http.StatusBadRequest,
+				},
+			)
+			return
+		}
+		seen[eachSplit.UserId] = true
+
+		split := models.GroupSplit{
+			UserId: eachSplit.UserId,
+			Shares: eachSplit.Shares,
+		}
+		if addGroupExpenseServiceRequestData.SplitType == "exact" {
+			split.Amount, err = money.Parse(eachSplit.Amount.String(), group.Currency)
+			if err != nil {
+				SendResponse(
+					s.channel,
+					replyTo,
+					correlationId,
+					"AddGroupExpenseResponse",
+					addGroupExpenseServiceResponse{
+						Message: "\"Amount\" of a split must be an amount in the group currency!",
+						Success: false,
+						Status: http.StatusBadRequest,
+					},
+				)
+				return
+			}
+		}
+		splits = append(splits, split)
+	}
+
+	splits, err = splitGroupExpense(amount, addGroupExpenseServiceRequestData.SplitType, splits)
+	if err != nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupExpenseResponse",
+			addGroupExpenseServiceResponse{
+				Message: err.Error(),
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+
+	now := time.Now().UTC()
+	date := addGroupExpenseServiceRequestData.Date
+	if date.IsZero() {
+		date = now
+	}
+
+	groupExpense := models.GroupExpense{
+		GroupExpenseId: uuid.New().String(),
+		GroupId:        group.GroupId,
+		Description:    addGroupExpenseServiceRequestData.Description,
+		Amount:         amount,
+		Currency:       group.Currency,
+		PaidBy:         paidBy,
+		SplitType:      addGroupExpenseServiceRequestData.SplitType,
+		Splits:         splits,
+		Date:           date,
+		CreatedBy:      addGroupExpenseServiceRequestData.UserId,
+		CreatedAt:      now,
+	}
+	result, err := s.groupExpenseRepo.Insert(context.Background(), groupExpense)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddGroupExpenseResponse",
+			addGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AddGroupExpenseResponse",
+		addGroupExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			GroupExpenseId: groupExpense.GroupExpenseId,
+		},
+	)
+}
+
+type removeGroupExpenseServiceRequest struct {
+	Action			string	`json:"action"`
+	UserId			string	`json:"userId"`
+	GroupExpenseId	string	`json:"groupExpenseId"`
+}
+
+type removeGroupExpenseServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *GroupService) HandleRemoveGroupExpense(data []byte, replyTo string, correlationId string) {
+	removeGroupExpenseServiceRequestData := removeGroupExpenseServiceRequest{}
+	err := json.Unmarshal(data, &removeGroupExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupExpenseResponse",
+			removeGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"groupExpenseId": removeGroupExpenseServiceRequestData.GroupExpenseId,
+	}
+	result, err := s.groupExpenseRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupExpenseResponse",
+			removeGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupExpenseResponse",
+			removeGroupExpenseServiceResponse{
+				Message: "Group expense not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	groupId, _ := result.Data[0]["groupId"].(string)
+	group, err := s.findGroup(context.Background(), groupId, removeGroupExpenseServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupExpenseResponse",
+			removeGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if group == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupExpenseResponse",
+			removeGroupExpenseServiceResponse{
+				Message: "Group expense not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	result, err = s.groupExpenseRepo.Delete(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveGroupExpenseResponse",
+			removeGroupExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RemoveGroupExpenseResponse",
+		removeGroupExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type getGroupBalanceServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type groupBalanceServiceResponse struct {
+	UserId	string	`json:"userId"`
+	Name	string	`json:"name"`
+	Balance	string	`json:"balance"`
+}
+
+type groupDebtServiceResponse struct {
+	FromUserId	string	`json:"fromUserId"`
+	ToUserId	string	`json:"toUserId"`
+	Amount		string	`json:"amount"`
+}
+
+type getGroupBalanceServiceResponse struct {
+	Message		string							`json:"message"`
+	Success		bool							`json:"success"`
+	Status		int								`json:"status,omitempty"`
+	Currency	string							`json:"currency"`
+	Balances	[]groupBalanceServiceResponse	`json:"balances"`
+	Debts		[]groupDebtServiceResponse		`json:"debts"`
+}
+
+func (s *GroupService) HandleGetGroupBalance(data []byte, replyTo string, correlationId string) {
+	getGroupBalanceServiceRequestData := getGroupBalanceServiceRequest{}
+	err := json.Unmarshal(data, &getGroupBalanceServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupBalanceResponse",
+			getGroupBalanceServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	group, err := s.findGroup(context.Background(), getGroupBalanceServiceRequestData.GroupId, getGroupBalanceServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupBalanceResponse",
+			getGroupBalanceServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if group == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupBalanceResponse",
+			getGroupBalanceServiceResponse{
+				Message: "Group not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	balances, err := s.groupBalances(context.Background(), group)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetGroupBalanceResponse",
+			getGroupBalanceServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	// Former members are listed as long as their balance is not settled.
+	names := make(map[string]string)
+	userIds := make([]string, 0, len(balances))
+	for _, member := range group.Members {
+		names[member.UserId] = member.Name
+		userIds = append(userIds, member.UserId)
+	}
+	for userId, balance := range balances {
+		if _, exists := names[userId]; !exists && balance != 0 {
+			userIds = append(userIds, userId)
+		}
+	}
+
+	memberBalances := make([]groupBalanceServiceResponse, 0, len(userIds))
+	for _, userId := range userIds {
+		memberBalances = append(memberBalances, groupBalanceServiceResponse{
+			UserId:  userId,
+			Name:    names[userId],
+			Balance: money.Format(balances[userId], group.Currency),
+		})
+	}
+	debts := make([]groupDebtServiceResponse, 0)
+	for _, debt := range settleUp(balances) {
+		debts = append(debts, groupDebtServiceResponse{
+			FromUserId: debt.FromUserId,
+			ToUserId:   debt.ToUserId,
+			Amount:     money.Format(debt.Amount, group.Currency),
+		})
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetGroupBalanceResponse",
+		getGroupBalanceServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Currency: group.Currency,
+			Balances: memberBalances,
+			Debts: debts,
+		},
+	)
+}
+
+type getSettlementServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	GroupId	string	`json:"groupId"`
+}
+
+type getSettlementServiceResponse struct {
+	Message		string						`json:"message"`
+	Success		bool						`json:"success"`
+	Status		int							`json:"status,omitempty"`
+	Settlements	[]map[string]interface{}	`json:"settlements"`
+}
+
+func (s *GroupService) HandleGetSettlement(data []byte, replyTo string, correlationId string) {
+	getSettlementServiceRequestData := getSettlementServiceRequest{}
+	err := json.Unmarshal(data, &getSettlementServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetSettlementResponse",
+			getSettlementServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	group, err := s.findGroup(context.Background(), getSettlementServiceRequestData.GroupId, getSettlementServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetSettlementResponse",
+			getSettlementServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if group == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetSettlementResponse",
+			getSettlementServiceResponse{
+				Message: "Group not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"groupId": group.GroupId,
+	}
+	result, err := s.settlementRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetSettlementResponse",
+			getSettlementServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	settlements := result.Data
+	for _, settlement := range settlements {
+		formatAmount(settlement, "")
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetSettlementResponse",
+		getSettlementServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Settlements: settlements,
+		},
+	)
+}
+
+type addSettlementServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	GroupId		string		`json:"groupId"`
+	FromUserId	string		`json:"fromUserId"`
+	ToUserId	string		`json:"toUserId"`
+	Amount		json.Number	`json:"amount"`
+	Date		time.Time	`json:"date"`
+}
+
+type addSettlementServiceResponse struct {
+	Message			string	`json:"message"`
+	Success			bool	`json:"success"`
+	Status			int		`json:"status,omitempty"`
+	SettlementId	string	`json:"settlementId"`
+}
+
+func (s *GroupService) HandleAddSettlement(data []byte, replyTo string, correlationId string) {
+	addSettlementServiceRequestData := addSettlementServiceRequest{}
+	err := json.Unmarshal(data, &addSettlementServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddSettlementResponse",
+			addSettlementServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	group, err := s.findGroup(context.Background(), addSettlementServiceRequestData.GroupId, addSettlementServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddSettlementResponse",
+			addSettlementServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if group == nil {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddSettlementResponse",
+			addSettlementServiceResponse{
+				Message: "Group not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	fromUserId := addSettlementServiceRequestData.FromUserId
+	if fromUserId == "" {
+		fromUserId = addSettlementServiceRequestData.UserId
+	}
+	toUserId := addSettlementServiceRequestData.ToUserId
+	if !isGroupMember(group, fromUserId) || !isGroupMember(group, toUserId) || fromUserId == toUserId {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddSettlementResponse",
+			addSettlementServiceResponse{
+				Message: "A settlement must be between two different members of the group!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+
+	amount, err := money.Parse(addSettlementServiceRequestData.Amount.String(), group.Currency)
+	if err != nil || amount <= 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddSettlementResponse",
+			addSettlementServiceResponse{
+				Message: "\"Amount\" must be a positive amount in the group currency!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+
+	now := time.Now().UTC()
+	date := addSettlementServiceRequestData.Date
+	if date.IsZero() {
+		date = now
+	}
+
+	settlement := models.Settlement{
+		SettlementId: uuid.New().String(),
+		GroupId:      group.GroupId,
+		FromUserId:   fromUserId,
+		ToUserId:     toUserId,
+		Amount:       amount,
+		Currency:     group.Currency,
+		Date:         date,
+		CreatedBy:    addSettlementServiceRequestData.UserId,
+		CreatedAt:    now,
+	}
+	result, err := s.settlementRepo.Insert(context.Background(), settlement)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddSettlementResponse",
+			addSettlementServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AddSettlementResponse",
+		addSettlementServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			SettlementId: settlement.SettlementId,
+		},
+	)
+}
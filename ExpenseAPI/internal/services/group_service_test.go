@@ -0,0 +1,118 @@
+package services
+
+import (
+	"testing"
+
+	"expense/internal/models"
+)
+
+func TestSplitGroupExpense(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    int64
+		splitType string
+		splits    []models.GroupSplit
+		want      []int64
+		wantErr   bool
+	}{
+		{
+			name:      "equal with remainder",
+			amount:    1000,
+			splitType: "equal",
+			splits:    []models.GroupSplit{{UserId: "a"}, {UserId: "b"}, {UserId: "c"}},
+			want:      []int64{334, 333, 333},
+		},
+		{
+			name:      "equal single cent",
+			amount:    1,
+			splitType: "equal",
+			splits:    []models.GroupSplit{{UserId: "a"}, {UserId: "b"}},
+			want:      []int64{1, 0},
+		},
+		{
+			name:      "shares uneven",
+			amount:    1000,
+			splitType: "shares",
+			splits:    []models.GroupSplit{{UserId: "a", Shares: 1}, {UserId: "b", Shares: 1}, {UserId: "c", Shares: 1}},
+			want:      []int64{334, 333, 333},
+		},
+		{
+			name:      "shares largest remainder first",
+			amount:    100,
+			splitType: "shares",
+			splits:    []models.GroupSplit{{UserId: "a", Shares: 1}, {UserId: "b", Shares: 2}, {UserId: "c", Shares: 4}},
+			want:      []int64{14, 29, 57},
+		},
+		{
+			name:      "shares large amount",
+			amount:    9000000000000000000,
+			splitType: "shares",
+			splits:    []models.GroupSplit{{UserId: "a", Shares: 3}, {UserId: "b", Shares: 7}},
+			want:      []int64{2700000000000000000, 6300000000000000000},
+		},
+		{
+			name:      "shares must be positive",
+			amount:    100,
+			splitType: "shares",
+			splits:    []models.GroupSplit{{UserId: "a", Shares: 1}, {UserId: "b"}},
+			wantErr:   true,
+		},
+		{
+			name:      "exact",
+			amount:    1000,
+			splitType: "exact",
+			splits:    []models.GroupSplit{{UserId: "a", Amount: 250}, {UserId: "b", Amount: 750}},
+			want:      []int64{250, 750},
+		},
+		{
+			name:      "exact must add up",
+			amount:    1000,
+			splitType: "exact",
+			splits:    []models.GroupSplit{{UserId: "a", Amount: 250}, {UserId: "b", Amount: 700}},
+			wantErr:   true,
+		},
+		{
+			name:      "no members",
+			amount:    1000,
+			splitType: "equal",
+			wantErr:   true,
+		},
+		{
+			name:      "unknown split type",
+			amount:    1000,
+			splitType: "percent",
+			splits:    []models.GroupSplit{{UserId: "a"}},
+			wantErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := splitGroupExpense(test.amount, test.splitType, test.splits)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("splitGroupExpense() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitGroupExpense() returned %v", err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("splitGroupExpense() returned %d splits, want %d", len(got), len(test.want))
+			}
+			total := int64(0)
+			for i, split := range got {
+				if split.UserId != test.splits[i].UserId {
+					t.Errorf("split %d belongs to %q, want %q", i, split.UserId, test.splits[i].UserId)
+				}
+				if split.Amount != test.want[i] {
+					t.Errorf("split %d = %d, want %d", i, split.Amount, test.want[i])
+				}
+				total += split.Amount
+			}
+			if total != test.amount {
+				t.Errorf("splits add up to %d, want %d", total, test.amount)
+			}
+		})
+	}
+}
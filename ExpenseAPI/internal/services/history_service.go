@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/models"
+	"expense/internal/repositories"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newExpenseHistory describes a change of an entry. A nil snapshot stands for
+// an entry that does not exist or is in the trash; the actor is empty for
+// changes made by the service itself.
+func newExpenseHistory(userId string, expenseId string, actorId string, action string, before interface{}, after interface{}) models.ExpenseHistory {
+	return models.ExpenseHistory{
+		HistoryId: uuid.New().String(),
+		ExpenseId: expenseId,
+		UserId:    userId,
+		ActorId:   actorId,
+		Action:    action,
+		Before:    before,
+		After:     after,
+		ChangedAt: time.Now().UTC(),
+	}
+}
+
+// recordHistory stores changes of entries. It takes the context of the change,
+// so that the history is rolled back with it in a transaction.
+func (s *ExpenseService) recordHistory(ctx context.Context, histories ...models.ExpenseHistory) error {
+	documents := make([]interface{}, 0, len(histories))
+	for _, history := range histories {
+		documents = append(documents, history)
+	}
+	result, err := s.historyRepo.InsertMany(ctx, documents)
+	if !result.Success {
+		return err
+	}
+	return nil
+}
+
+// patchedDocument returns a copy of a stored entry with some fields replaced,
+// as the snapshot after an update that only sets those fields.
+func patchedDocument(document map[string]interface{}, fields map[string]interface{}) map[string]interface{} {
+	patched := make(map[string]interface{}, len(document)+len(fields))
+	for key, value := range document {
+		patched[key] = value
+	}
+	for key, value := range fields {
+		patched[key] = value
+	}
+	return patched
+}
+
+// formatSnapshot formats the amount of a snapshot the way entries are returned.
+func formatSnapshot(snapshot interface{}) {
+	if document, ok := snapshot.(map[string]interface{}); ok {
+		formatAmount(document, "")
+	}
+}
+
+type getExpenseHistoryServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+}
+
+type getExpenseHistoryServiceResponse struct {
+	Message	string						`json:"message"`
+	Success	bool						`json:"success"`
+	Status	int							`json:"status,omitempty"`
+	History	[]map[string]interface{}	`json:"history"`
+}
+
+func (s *ExpenseService) HandleGetExpenseHistory(data []byte, replyTo string, correlationId string) {
+	getExpenseHistoryServiceRequestData := getExpenseHistoryServiceRequest{}
+	err := json.Unmarshal(data, &getExpenseHistoryServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetExpenseHistoryResponse",
+			getExpenseHistoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":    getExpenseHistoryServiceRequestData.UserId,
+		"expenseId": getExpenseHistoryServiceRequestData.ExpenseId,
+	}
+	result, err := s.historyRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetExpenseHistoryResponse",
+			getExpenseHistoryServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	history := result.Data
+
+	// Entries stored before the history was kept have none, but still exist.
+	if len(history) == 0 {
+		result, err = s.mongoDBRepo.Find(context.Background(), filter)
+		if !result.Success {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"GetExpenseHistoryResponse",
+				getExpenseHistoryServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+		if len(result.Data) == 0 {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"GetExpenseHistoryResponse",
+				getExpenseHistoryServiceResponse{
+					Message: "Expense not found!",
+					Success: false,
+					Status: http.StatusNotFound,
+				},
+			)
+			return
+		}
+		history = []map[string]interface{}{}
+	}
+
+	for _, change := range history {
+		formatSnapshot(change["before"])
+		formatSnapshot(change["after"])
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return documentTime(history[i]["changedAt"]).After(documentTime(history[j]["changedAt"]))
+	})
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetExpenseHistoryResponse",
+		getExpenseHistoryServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			History: history,
+		},
+	)
+}
+
+type revertExpenseServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+	HistoryId	string	`json:"historyId"`
+}
+
+type revertExpenseServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+// revertExpense brings an entry back to its state after a change of its
+// history, restoring it from the trash if needed.
+func (s *ExpenseService) revertExpense(ctx context.Context, revertExpenseServiceRequestData revertExpenseServiceRequest) error {
+	historyFilter := map[string]interface{}{
+		"userId":    revertExpenseServiceRequestData.UserId,
+		"expenseId": revertExpenseServiceRequestData.ExpenseId,
+		"historyId": revertExpenseServiceRequestData.HistoryId,
+	}
+	result, err := s.historyRepo.Find(ctx, historyFilter)
+	if !result.Success {
+		return err
+	}
+	if len(result.Data) == 0 {
+		return &entryError{status: http.StatusNotFound, message: "Version not found!"}
+	}
+	snapshot, ok := result.Data[0]["after"].(map[string]interface{})
+	if !ok {
+		return &entryError{status: http.StatusBadRequest, message: "The expense did not exist after this change!"}
+	}
+
+	filter := map[string]interface{}{
+		"userId":    revertExpenseServiceRequestData.UserId,
+		"expenseId": revertExpenseServiceRequestData.ExpenseId,
+	}
+	result, err = s.mongoDBRepo.Find(ctx, filter)
+	if !result.Success {
+		return err
+	}
+	if len(result.Data) == 0 {
+		return &entryError{status: http.StatusNotFound, message: "Expense not found!"}
+	}
+	current := result.Data[0]
+
+	// Accounts and categories may have changed since, so the version is checked like a new entry.
+	expense := models.Expense{}
+	if err := decodeDocument(snapshot, &expense); err != nil {
+		return err
+	}
+	err = s.validateEntryAccounts(ctx, expense.UserId, expense.AccountId, expense.ToAccountId)
+	if err == errUnknownAccount {
+		return &entryError{status: http.StatusBadRequest, message: "Account is unknown!"}
+	}
+	if err != nil {
+		return err
+	}
+	category, err := s.resolveEntryCategory(ctx, expense.UserId, expense.Category)
+	if err == errUnknownCategory {
+		return &entryError{status: http.StatusBadRequest, message: "Category is unknown!"}
+	}
+	if err != nil {
+		return err
+	}
+
+	version := documentVersion(current)
+	reverted := patchedDocument(snapshot, map[string]interface{}{
+		"category":  category,
+		"updatedAt": time.Now().UTC(),
+		"version":   version + 1,
+	})
+	unset := map[string]interface{}{}
+	for key := range current {
+		if _, ok := reverted[key]; !ok {
+			unset[key] = ""
+		}
+	}
+	update := map[string]interface{}{"$set": reverted}
+	if len(unset) != 0 {
+		update["$unset"] = unset
+	}
+	filter["version"] = versionFilter(version)
+	result, err = s.mongoDBRepo.UpdateIfMatch(ctx, filter, update)
+	if err == repositories.ErrNoMatch {
+		return errStaleVersion
+	}
+	if !result.Success {
+		return err
+	}
+
+	var before interface{}
+	if _, deleted := current["deletedAt"]; !deleted {
+		before = current
+	}
+	return s.recordHistory(ctx, newExpenseHistory(expense.UserId, expense.ExpenseId, revertExpenseServiceRequestData.UserId, "revert", before, reverted))
+}
+
+func (s *ExpenseService) HandleRevertExpense(data []byte, replyTo string, correlationId string) {
+	revertExpenseServiceRequestData := revertExpenseServiceRequest{}
+	err := json.Unmarshal(data, &revertExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RevertExpenseResponse",
+			revertExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	err = s.revertExpense(context.Background(), revertExpenseServiceRequestData)
+	if entryErr, ok := err.(*entryError); ok {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RevertExpenseResponse",
+			revertExpenseServiceResponse{
+				Message: entryErr.message,
+				Success: false,
+				Status: entryErr.status,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RevertExpenseResponse",
+			revertExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RevertExpenseResponse",
+		revertExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/importer"
+	"expense/internal/models"
+	"log"
+	"sort"
+)
+
+const importBatchSize = 500
+
+// importEntries turns imported rows into expenses the way new entries are
+// built, so rules, categories and anomaly checks apply to them alike. Rows that
+// cannot be imported are reported instead.
+func (s *ExpenseService) importEntries(ctx context.Context, userId string, rows []importer.Row) ([]models.Expense, []importer.RowError, error) {
+	expenses := make([]models.Expense, 0, len(rows))
+	rowErrors := make([]importer.RowError, 0)
+	for _, row := range rows {
+		expense, err := s.newExpense(ctx, addExpenseServiceRequest{
+			UserId:      userId,
+			Description: row.Description,
+			Payee:       row.Payee,
+			Type:        row.Type,
+			Amount:      json.Number(row.Amount),
+			Currency:    row.Currency,
+			Category:    row.Category,
+			Date:        row.Date,
+		})
+		if entryErr, ok := err.(*entryError); ok {
+			rowErrors = append(rowErrors, importer.RowError{Line: row.Line, Message: entryErr.message})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		expense.ExternalId = row.ExternalId
+		expenses = append(expenses, expense)
+	}
+	return expenses, rowErrors, nil
+}
+
+// skipDuplicates drops expenses whose external id was imported before or
+// appears earlier in the same import, and returns how many were dropped.
+func (s *ExpenseService) skipDuplicates(ctx context.Context, userId string, expenses []models.Expense) ([]models.Expense, int, error) {
+	externalIds := make([]string, 0, len(expenses))
+	for _, expense := range expenses {
+		if expense.ExternalId != "" {
+			externalIds = append(externalIds, expense.ExternalId)
+		}
+	}
+	if len(externalIds) == 0 {
+		return expenses, 0, nil
+	}
+
+	filter := map[string]interface{}{
+		"userId":     userId,
+		"externalId": map[string]interface{}{"$in": externalIds},
+	}
+	result, err := s.mongoDBRepo.Find(ctx, filter)
+	if !result.Success {
+		return nil, 0, err
+	}
+	seen := make(map[string]bool)
+	for _, document := range result.Data {
+		if externalId, ok := document["externalId"].(string); ok {
+			seen[externalId] = true
+		}
+	}
+
+	unique := make([]models.Expense, 0, len(expenses))
+	for _, expense := range expenses {
+		if expense.ExternalId != "" {
+			if seen[expense.ExternalId] {
+				continue
+			}
+			seen[expense.ExternalId] = true
+		}
+		unique = append(unique, expense)
+	}
+	return unique, len(expenses) - len(unique), nil
+}
+
+// insertExpenses stores expenses in batches and returns how many were stored
+// and how many were skipped, because an import running at the same time
+// stored their external ids first.
+func (s *ExpenseService) insertExpenses(ctx context.Context, expenses []models.Expense) (int, int, error) {
+	inserted, duplicates := 0, 0
+	for start := 0; start < len(expenses); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(expenses) {
+			end = len(expenses)
+		}
+		documents := make([]interface{}, 0, end-start)
+		for _, expense := range expenses[start:end] {
+			documents = append(documents, expense)
+		}
+		skipped, err := s.mongoDBRepo.InsertNew(ctx, documents)
+		if err != nil {
+			return inserted, duplicates, err
+		}
+		isSkipped := make(map[int]bool, len(skipped))
+		for _, index := range skipped {
+			isSkipped[index] = true
+		}
+		histories := make([]models.ExpenseHistory, 0, end-start)
+		for index, expense := range expenses[start:end] {
+			if !isSkipped[index] {
+				histories = append(histories, newExpenseHistory(expense.UserId, expense.ExpenseId, expense.UserId, "create", nil, expense))
+			}
+		}
+		inserted += len(histories)
+		duplicates += len(skipped)
+		if err := s.recordHistory(ctx, histories...); err != nil {
+			return inserted, duplicates, err
+		}
+	}
+	return inserted, duplicates, nil
+}
+
+type importExpensesServiceRequest struct {
+	Action	string				`json:"action"`
+	UserId	string				`json:"userId"`
+	DryRun	bool				`json:"dryRun"`
+	Rows	[]importer.Row		`json:"rows"`
+	Errors	[]importer.RowError	`json:"errors"`
+}
+
+type importExpensesServiceResponse struct {
+	Message		string				`json:"message"`
+	Success		bool				`json:"success"`
+	DryRun		bool				`json:"dryRun"`
+	Valid		int					`json:"valid"`
+	Imported	int					`json:"imported"`
+	Duplicates	int					`json:"duplicates"`
+	Errors		[]importer.RowError	`json:"errors"`
+}
+
+// HandleImportExpenses validates imported rows and, unless it is a dry run,
+// stores the valid ones. Invalid rows are skipped and reported by line, rows
+// that were imported before are skipped and counted.
+func (s *ExpenseService) HandleImportExpenses(data []byte, replyTo string, correlationId string) {
+	importExpensesServiceRequestData := importExpensesServiceRequest{}
+	err := json.Unmarshal(data, &importExpensesServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"ImportExpensesResponse",
+			importExpensesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	ctx := context.Background()
+	expenses, rowErrors, err := s.importEntries(ctx, importExpensesServiceRequestData.UserId, importExpensesServiceRequestData.Rows)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"ImportExpensesResponse",
+			importExpensesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	expenses, duplicates, err := s.skipDuplicates(ctx, importExpensesServiceRequestData.UserId, expenses)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"ImportExpensesResponse",
+			importExpensesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	rowErrors = append(rowErrors, importExpensesServiceRequestData.Errors...)
+	sort.SliceStable(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Line < rowErrors[j].Line
+	})
+
+	imported, skipped := 0, 0
+	if !importExpensesServiceRequestData.DryRun {
+		imported, skipped, err = s.insertExpenses(ctx, expenses)
+		if err != nil {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"ImportExpensesResponse",
+				importExpensesServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+					Imported: imported,
+				},
+			)
+			return
+		}
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"ImportExpensesResponse",
+		importExpensesServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			DryRun: importExpensesServiceRequestData.DryRun,
+			Valid: len(expenses) - skipped,
+			Imported: imported,
+			Duplicates: duplicates + skipped,
+			Errors: rowErrors,
+		},
+	)
+}
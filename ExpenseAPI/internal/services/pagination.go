@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var errInvalidCursor = errors.New("cursor is invalid")
+
+// expenseCursor marks the last entry of a page. It is BSON encoded so that the
+// sort value keeps its type, e.g. a date or a 64-bit amount.
+type expenseCursor struct {
+	Field		string		`bson:"field"`
+	Direction	int			`bson:"direction"`
+	Value		interface{}	`bson:"value"`
+	ExpenseId	string		`bson:"expenseId"`
+}
+
+func encodeCursor(cursor expenseCursor) (string, error) {
+	data, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(value string) (expenseCursor, error) {
+	cursor := expenseCursor{}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+	if err := bson.Unmarshal(data, &cursor); err != nil || cursor.ExpenseId == "" {
+		return cursor, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+// cursorCondition matches the entries after the cursor in its sort order,
+// which is the sort field followed by the expense id. Missing values sort
+// before any other value, and comparisons never match across types, so they
+// are handled on their own.
+func cursorCondition(cursor expenseCursor) map[string]interface{} {
+	after := func(value interface{}) map[string]interface{} {
+		if cursor.Direction < 0 {
+			return map[string]interface{}{"$lt": value}
+		}
+		return map[string]interface{}{"$gt": value}
+	}
+	tie := map[string]interface{}{
+		cursor.Field: cursor.Value,
+		"expenseId":  after(cursor.ExpenseId),
+	}
+
+	if cursor.Value == nil {
+		if cursor.Direction < 0 {
+			return tie
+		}
+		return map[string]interface{}{"$or": []interface{}{
+			map[string]interface{}{cursor.Field: map[string]interface{}{"$ne": nil}},
+			tie,
+		}}
+	}
+	conditions := []interface{}{
+		map[string]interface{}{cursor.Field: after(cursor.Value)},
+		tie,
+	}
+	if cursor.Direction < 0 {
+		conditions = append(conditions, map[string]interface{}{cursor.Field: nil})
+	}
+	return map[string]interface{}{"$or": conditions}
+}
+
+// expenseProjection selects the requested fields together with those needed
+// to convert amounts and to continue after the page.
+func expenseProjection(fields []string, sortField string) map[string]interface{} {
+	projection := map[string]interface{}{"_id": 0}
+	if len(fields) == 0 {
+		return projection
+	}
+	for _, field := range fields {
+		projection[field] = 1
+	}
+	for _, field := range []string{"expenseId", "type", "amount", "currency", "date", "createdAt", sortField} {
+		if field != "" {
+			projection[field] = 1
+		}
+	}
+	return projection
+}
+
+// selectFields drops what was only read for internal use. The converted
+// amount belongs to the amount.
+func selectFields(expense map[string]interface{}, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	selected := make(map[string]bool)
+	for _, field := range fields {
+		selected[field] = true
+	}
+	for key := range expense {
+		switch key {
+		case "baseAmount", "baseCurrency", "exchangeRate":
+			if selected["amount"] {
+				continue
+			}
+		}
+		if !selected[key] {
+			delete(expense, key)
+		}
+	}
+}
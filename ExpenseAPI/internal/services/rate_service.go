@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"expense/internal/rates"
+	"log"
+	"math/big"
+	"os"
+)
+
+// loadRates fills the in-memory rate table from the rates collection and, when
+// RATES_FILE points to an ECB-style XML or CSV file, stores its rates as well.
+func (s *ExpenseService) loadRates() error {
+	ctx := context.Background()
+
+	result, err := s.ratesRepo.Find(ctx, map[string]interface{}{})
+	if !result.Success {
+		return err
+	}
+
+	stored := make([]rates.Rate, 0, len(result.Data))
+	for _, document := range result.Data {
+		currency, _ := document["currency"].(string)
+		value, _ := document["rate"].(string)
+		rate, ok := new(big.Rat).SetString(value)
+		if currency == "" || !ok {
+			continue
+		}
+		stored = append(stored, rates.Rate{
+			Date:     documentTime(document["date"]),
+			Currency: currency,
+			Value:    rate,
+		})
+	}
+	s.rates.Add(stored...)
+
+	ratesFile := os.Getenv("RATES_FILE")
+	if ratesFile == "" {
+		return nil
+	}
+
+	loaded, err := rates.LoadFile(ratesFile)
+	if err != nil {
+		return err
+	}
+	s.rates.Add(loaded...)
+
+	filters := make([]interface{}, 0, len(loaded))
+	updates := make([]interface{}, 0, len(loaded))
+	for _, rate := range loaded {
+		filters = append(filters, map[string]interface{}{
+			"date":     rate.Date,
+			"currency": rate.Currency,
+		})
+		updates = append(updates, map[string]interface{}{
+			"$set": map[string]interface{}{
+				"rate": rates.FormatRate(rate.Value),
+			},
+		})
+	}
+	result, err = s.ratesRepo.UpsertMany(ctx, filters, updates)
+	if !result.Success {
+		return err
+	}
+
+	log.Printf("Loaded %d exchange rates from %s.", len(loaded), ratesFile)
+	return nil
+}
+
+// convertExpenses annotates each entry document with its amount in the base
+// currency and returns the converted expense and income sums along with the
+// expense totals per original currency.
+func (s *ExpenseService) convertExpenses(expenses []map[string]interface{}, baseCurrency string) (int64, int64, map[string]int64, bool) {
+	total := int64(0)
+	income := int64(0)
+	totals := make(map[string]int64)
+	complete := true
+
+	for _, expense := range expenses {
+		amount, currency := documentAmount(expense)
+		entryType := entryType(expense)
+		if entryType == "expense" {
+			totals[currency] += amount
+		}
+
+		converted, rate, err := s.rates.Convert(amount, currency, baseCurrency, expenseDate(expense))
+		if err != nil {
+			complete = false
+			continue
+		}
+		expense["baseAmount"] = converted
+		expense["exchangeRate"] = rates.FormatRate(rate)
+		// Transfers only move money between accounts, so they count towards neither sum.
+		switch entryType {
+		case "expense":
+			total += converted
+		case "income":
+			income += converted
+		}
+	}
+	return total, income, totals, complete
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/models"
+	"expense/internal/repositories"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// removeExpenseReceipts deletes the receipts of an expense together with their blobs.
+func (s *ExpenseService) removeExpenseReceipts(ctx context.Context, userId string, expenseId string) error {
+	filter := map[string]interface{}{
+		"userId":    userId,
+		"expenseId": expenseId,
+	}
+	result, err := s.receiptRepo.Find(ctx, filter)
+	if !result.Success {
+		return err
+	}
+	for _, document := range result.Data {
+		blobId, _ := document["blobId"].(string)
+		if err := s.receiptStore.Delete(ctx, blobId); err != nil && err != repositories.ErrBlobNotFound {
+			return err
+		}
+	}
+	result, err = s.receiptRepo.DeleteMany(ctx, filter)
+	if !result.Success {
+		return err
+	}
+	return nil
+}
+
+type getReceiptServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ReceiptId	string	`json:"receiptId"`
+	ExpenseId	string	`json:"expenseId"`
+}
+
+type getReceiptServiceResponse struct {
+	Message		string			`json:"message"`
+	Success		bool			`json:"success"`
+	Status		int				`json:"status,omitempty"`
+	Receipts	[]models.Receipt	`json:"receipts"`
+	Content		[]byte			`json:"content,omitempty"`
+}
+
+func (s *ExpenseService) HandleGetReceipt(data []byte, replyTo string, correlationId string) {
+	getReceiptServiceRequestData := getReceiptServiceRequest{}
+	err := json.Unmarshal(data, &getReceiptServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetReceiptResponse",
+			getReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": getReceiptServiceRequestData.UserId,
+	}
+	if getReceiptServiceRequestData.ReceiptId != "" {
+		filter["receiptId"] = getReceiptServiceRequestData.ReceiptId
+	}
+	if getReceiptServiceRequestData.ExpenseId != "" {
+		filter["expenseId"] = getReceiptServiceRequestData.ExpenseId
+	}
+	result, err := s.receiptRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetReceiptResponse",
+			getReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	receipts := make([]models.Receipt, 0, len(result.Data))
+	for _, document := range result.Data {
+		receipt := models.Receipt{}
+		if err := decodeDocument(document, &receipt); err != nil {
+			log.Println(err)
+			continue
+		}
+		// The blob id is not part of the JSON form, so it is copied over separately.
+		receipt.BlobId, _ = document["blobId"].(string)
+		receipts = append(receipts, receipt)
+	}
+
+	// A single receipt is returned together with its content.
+	var content []byte
+	if getReceiptServiceRequestData.ReceiptId != "" {
+		if len(receipts) == 0 {
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"GetReceiptResponse",
+				getReceiptServiceResponse{
+					Message: "Receipt not found!",
+					Success: false,
+					Status: http.StatusNotFound,
+				},
+			)
+			return
+		}
+		content, err = s.receiptStore.Download(context.Background(), receipts[0].BlobId)
+		if err != nil {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"GetReceiptResponse",
+				getReceiptServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetReceiptResponse",
+		getReceiptServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Receipts: receipts,
+			Content: content,
+		},
+	)
+}
+
+type addReceiptServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+	FileName	string	`json:"fileName"`
+	ContentType	string	`json:"contentType"`
+	Content		[]byte	`json:"content"`
+}
+
+type addReceiptServiceResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	Status		int		`json:"status,omitempty"`
+	ReceiptId	string	`json:"receiptId"`
+}
+
+func (s *ExpenseService) HandleAddReceipt(data []byte, replyTo string, correlationId string) {
+	addReceiptServiceRequestData := addReceiptServiceRequest{}
+	err := json.Unmarshal(data, &addReceiptServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddReceiptResponse",
+			addReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":    addReceiptServiceRequestData.UserId,
+		"expenseId": addReceiptServiceRequestData.ExpenseId,
+		"deletedAt": notDeleted(),
+	}
+	result, err := s.mongoDBRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddReceiptResponse",
+			addReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddReceiptResponse",
+			addReceiptServiceResponse{
+				Message: "Expense not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	blobId, err := s.receiptStore.Upload(context.Background(), addReceiptServiceRequestData.FileName, addReceiptServiceRequestData.ContentType, addReceiptServiceRequestData.Content)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddReceiptResponse",
+			addReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	receipt := models.Receipt{
+		ReceiptId:   uuid.New().String(),
+		ExpenseId:   addReceiptServiceRequestData.ExpenseId,
+		UserId:      addReceiptServiceRequestData.UserId,
+		BlobId:      blobId,
+		FileName:    addReceiptServiceRequestData.FileName,
+		ContentType: addReceiptServiceRequestData.ContentType,
+		Size:        int64(len(addReceiptServiceRequestData.Content)),
+		CreatedAt:   time.Now().UTC(),
+	}
+	result, err = s.receiptRepo.Insert(context.Background(), receipt)
+	if !result.Success {
+		log.Println(err)
+		if err := s.receiptStore.Delete(context.Background(), blobId); err != nil {
+			log.Println(err)
+		}
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddReceiptResponse",
+			addReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AddReceiptResponse",
+		addReceiptServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			ReceiptId: receipt.ReceiptId,
+		},
+	)
+}
+
+type removeReceiptServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ReceiptId	string	`json:"receiptId"`
+}
+
+type removeReceiptServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleRemoveReceipt(data []byte, replyTo string, correlationId string) {
+	removeReceiptServiceRequestData := removeReceiptServiceRequest{}
+	err := json.Unmarshal(data, &removeReceiptServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveReceiptResponse",
+			removeReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":    removeReceiptServiceRequestData.UserId,
+		"receiptId": removeReceiptServiceRequestData.ReceiptId,
+	}
+	result, err := s.receiptRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveReceiptResponse",
+			removeReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveReceiptResponse",
+			removeReceiptServiceResponse{
+				Message: "Receipt not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	blobId, _ := result.Data[0]["blobId"].(string)
+	err = s.receiptStore.Delete(context.Background(), blobId)
+	if err != nil && err != repositories.ErrBlobNotFound {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveReceiptResponse",
+			removeReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	result, err = s.receiptRepo.Delete(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveReceiptResponse",
+			removeReceiptServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RemoveReceiptResponse",
+		removeReceiptServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
@@ -0,0 +1,650 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/models"
+	"expense/internal/money"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const schedulerInterval = time.Minute
+
+// Occurrences get deterministic ids derived from this namespace, so that
+// materializing the same occurrence twice never creates a duplicate expense.
+var recurringNamespace = uuid.MustParse("6f1c2a9e-4b7d-4c3e-9a51-2d8e7f0b3c64")
+
+func occurrenceDate(recurringExpense models.RecurringExpense, index int) time.Time {
+	start := recurringExpense.StartDate
+	step := index * recurringExpense.Interval
+	switch recurringExpense.Frequency {
+	case "daily":
+		return start.AddDate(0, 0, step)
+	case "weekly":
+		return start.AddDate(0, 0, 7*step)
+	case "monthly":
+		return addMonthsClamped(start, step)
+	case "yearly":
+		return addMonthsClamped(start, 12*step)
+	}
+	return start
+}
+
+// addMonthsClamped keeps the day of month of the start date where possible and
+// falls back to the last day of shorter months (e.g. Jan 31 -> Feb 28).
+func addMonthsClamped(date time.Time, months int) time.Time {
+	year, month, day := date.Date()
+	firstOfMonth := time.Date(year, month+time.Month(months), 1, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
+
+// nextOccurrence returns the first occurrence after the given time (or the
+// first one at all), or nil when the schedule has ended.
+func nextOccurrence(recurringExpense models.RecurringExpense, after *time.Time) *time.Time {
+	if recurringExpense.Interval <= 0 {
+		return nil
+	}
+	for index := 0; index < 100000; index++ {
+		date := occurrenceDate(recurringExpense, index)
+		if after != nil && !date.After(*after) {
+			continue
+		}
+		if recurringExpense.EndDate != nil && date.After(*recurringExpense.EndDate) {
+			return nil
+		}
+		return &date
+	}
+	return nil
+}
+
+func (s *ExpenseService) runScheduler() {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	// Catch up on occurrences that became due while the service was down.
+	s.materializeRecurringExpenses()
+	s.purgeTrash()
+	for {
+		select {
+		case <-ticker.C:
+			s.materializeRecurringExpenses()
+			s.purgeTrash()
+		case <-s.schedulerStop:
+			return
+		}
+	}
+}
+
+func (s *ExpenseService) materializeRecurringExpenses() {
+	now := time.Now().UTC()
+	filter := map[string]interface{}{
+		"nextOccurrence": map[string]interface{}{"$lte": now},
+	}
+	result, err := s.recurringRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		return
+	}
+
+	for _, document := range result.Data {
+		recurringExpense := models.RecurringExpense{}
+		if err := decodeDocument(document, &recurringExpense); err != nil {
+			log.Println(err)
+			continue
+		}
+		if err := s.materializeRecurringExpense(context.Background(), recurringExpense, now); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (s *ExpenseService) materializeRecurringExpense(ctx context.Context, recurringExpense models.RecurringExpense, now time.Time) error {
+	filters := make([]interface{}, 0)
+	updates := make([]interface{}, 0)
+	historyFilters := make([]interface{}, 0)
+	historyUpdates := make([]interface{}, 0)
+
+	last := recurringExpense.LastOccurrence
+	next := recurringExpense.NextOccurrence
+	for next != nil && !next.After(now) {
+		expenseId := uuid.NewSHA1(recurringNamespace, []byte(recurringExpense.RecurringId+"/"+next.Format(time.RFC3339))).String()
+		expense := models.Expense{
+			ExpenseId:   expenseId,
+			UserId:      recurringExpense.UserId,
+			Description: recurringExpense.Description,
+			Type:        entryTypeOrDefault(recurringExpense.Type),
+			Amount:      recurringExpense.Amount,
+			Currency:    recurringExpense.Currency,
+			Category:    recurringExpense.Category,
+			Tags:        []string{},
+			Date:        *next,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			RecurringId: recurringExpense.RecurringId,
+			Version:     1,
+		}
+		filters = append(filters, map[string]interface{}{"expenseId": expenseId})
+		updates = append(updates, map[string]interface{}{"$setOnInsert": expense})
+		history := newExpenseHistory(expense.UserId, expenseId, "", "create", nil, expense)
+		history.HistoryId = uuid.NewSHA1(recurringNamespace, []byte(expenseId+"/create")).String()
+		historyFilters = append(historyFilters, map[string]interface{}{"historyId": history.HistoryId})
+		historyUpdates = append(historyUpdates, map[string]interface{}{"$setOnInsert": history})
+
+		last = next
+		next = nextOccurrence(recurringExpense, last)
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+
+	result, err := s.mongoDBRepo.UpsertMany(ctx, filters, updates)
+	if !result.Success {
+		return err
+	}
+	// Like the occurrences, their history is keyed by id and only recorded once.
+	result, err = s.historyRepo.UpsertMany(ctx, historyFilters, historyUpdates)
+	if !result.Success {
+		return err
+	}
+
+	// Only advance the schedule if nobody else did it in the meantime.
+	filter := map[string]interface{}{
+		"recurringId":    recurringExpense.RecurringId,
+		"nextOccurrence": recurringExpense.NextOccurrence,
+	}
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{
+			"lastOccurrence": last,
+			"nextOccurrence": next,
+		},
+	}
+	result, err = s.recurringRepo.Update(ctx, filter, update)
+	if !result.Success {
+		return err
+	}
+	return nil
+}
+
+type getRecurringExpenseServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	RecurringId	string	`json:"recurringId"`
+}
+
+type getRecurringExpenseServiceResponse struct {
+	Message				string						`json:"message"`
+	Success				bool						`json:"success"`
+	RecurringExpenses	[]map[string]interface{}	`json:"recurringExpenses"`
+}
+
+func (s *ExpenseService) HandleGetRecurringExpense(data []byte, replyTo string, correlationId string) {
+	getRecurringExpenseServiceRequestData := getRecurringExpenseServiceRequest{}
+	err := json.Unmarshal(data, &getRecurringExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetRecurringExpenseResponse",
+			getRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": getRecurringExpenseServiceRequestData.UserId,
+	}
+	if getRecurringExpenseServiceRequestData.RecurringId != "" {
+		filter["recurringId"] = getRecurringExpenseServiceRequestData.RecurringId
+	}
+	result, err := s.recurringRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetRecurringExpenseResponse",
+			getRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	recurringExpenses := result.Data
+	for _, recurringExpense := range recurringExpenses {
+		formatAmount(recurringExpense, "")
+	}
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetRecurringExpenseResponse",
+		getRecurringExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			RecurringExpenses: recurringExpenses,
+		},
+	)
+}
+
+type addRecurringExpenseServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	Description	string		`json:"description"`
+	Type		string		`json:"type"`
+	Amount		json.Number	`json:"amount"`
+	Currency	string		`json:"currency"`
+	Category	string		`json:"category"`
+	Frequency	string		`json:"frequency"`
+	Interval	int			`json:"interval"`
+	StartDate	time.Time	`json:"startDate"`
+	EndDate		*time.Time	`json:"endDate"`
+}
+
+type addRecurringExpenseServiceResponse struct {
+	Message		string	`json:"message"`
+	Success		bool	`json:"success"`
+	Status		int		`json:"status,omitempty"`
+	RecurringId	string	`json:"recurringId"`
+}
+
+func (s *ExpenseService) HandleAddRecurringExpense(data []byte, replyTo string, correlationId string) {
+	addRecurringExpenseServiceRequestData := addRecurringExpenseServiceRequest{}
+	err := json.Unmarshal(data, &addRecurringExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRecurringExpenseResponse",
+			addRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	amount, err := money.Parse(addRecurringExpenseServiceRequestData.Amount.String(), addRecurringExpenseServiceRequestData.Currency)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRecurringExpenseResponse",
+			addRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	category, err := s.resolveEntryCategory(context.Background(), addRecurringExpenseServiceRequestData.UserId, addRecurringExpenseServiceRequestData.Category)
+	if err == errUnknownCategory {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRecurringExpenseResponse",
+			addRecurringExpenseServiceResponse{
+				Message: "Category is unknown!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRecurringExpenseResponse",
+			addRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	now := time.Now().UTC()
+	recurringExpense := models.RecurringExpense{
+		RecurringId: uuid.New().String(),
+		UserId:      addRecurringExpenseServiceRequestData.UserId,
+		Description: addRecurringExpenseServiceRequestData.Description,
+		Type:        entryTypeOrDefault(addRecurringExpenseServiceRequestData.Type),
+		Amount:      amount,
+		Currency:    addRecurringExpenseServiceRequestData.Currency,
+		Category:    category,
+		Frequency:   addRecurringExpenseServiceRequestData.Frequency,
+		Interval:    addRecurringExpenseServiceRequestData.Interval,
+		StartDate:   addRecurringExpenseServiceRequestData.StartDate,
+		EndDate:     addRecurringExpenseServiceRequestData.EndDate,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	recurringExpense.NextOccurrence = nextOccurrence(recurringExpense, nil)
+
+	result, err := s.recurringRepo.Insert(context.Background(), recurringExpense)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRecurringExpenseResponse",
+			addRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	// Occurrences that are already due (e.g. a start date in the past) are created right away.
+	if err := s.materializeRecurringExpense(context.Background(), recurringExpense, now); err != nil {
+		log.Println(err)
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AddRecurringExpenseResponse",
+		addRecurringExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			RecurringId: recurringExpense.RecurringId,
+		},
+	)
+}
+
+type updateRecurringExpenseServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	RecurringId	string		`json:"recurringId"`
+	Description	string		`json:"description"`
+	Type		string		`json:"type"`
+	Amount		json.Number	`json:"amount"`
+	Currency	string		`json:"currency"`
+	Category	string		`json:"category"`
+	Frequency	string		`json:"frequency"`
+	Interval	int			`json:"interval"`
+	StartDate	time.Time	`json:"startDate"`
+	EndDate		*time.Time	`json:"endDate"`
+}
+
+type updateRecurringExpenseServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleUpdateRecurringExpense(data []byte, replyTo string, correlationId string) {
+	updateRecurringExpenseServiceRequestData := updateRecurringExpenseServiceRequest{}
+	err := json.Unmarshal(data, &updateRecurringExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRecurringExpenseResponse",
+			updateRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	amount, err := money.Parse(updateRecurringExpenseServiceRequestData.Amount.String(), updateRecurringExpenseServiceRequestData.Currency)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRecurringExpenseResponse",
+			updateRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":      updateRecurringExpenseServiceRequestData.UserId,
+		"recurringId": updateRecurringExpenseServiceRequestData.RecurringId,
+	}
+	result, err := s.recurringRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRecurringExpenseResponse",
+			updateRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRecurringExpenseResponse",
+			updateRecurringExpenseServiceResponse{
+				Message: "Recurring expense not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	recurringExpense := models.RecurringExpense{}
+	if err := decodeDocument(result.Data[0], &recurringExpense); err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRecurringExpenseResponse",
+			updateRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	category, err := s.resolveEntryCategory(context.Background(), updateRecurringExpenseServiceRequestData.UserId, updateRecurringExpenseServiceRequestData.Category)
+	if err == errUnknownCategory {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRecurringExpenseResponse",
+			updateRecurringExpenseServiceResponse{
+				Message: "Category is unknown!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRecurringExpenseResponse",
+			updateRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	recurringExpense.Description = updateRecurringExpenseServiceRequestData.Description
+	recurringExpense.Type = entryTypeOrDefault(updateRecurringExpenseServiceRequestData.Type)
+	recurringExpense.Amount = amount
+	recurringExpense.Currency = updateRecurringExpenseServiceRequestData.Currency
+	recurringExpense.Category = category
+	recurringExpense.Frequency = updateRecurringExpenseServiceRequestData.Frequency
+	recurringExpense.Interval = updateRecurringExpenseServiceRequestData.Interval
+	recurringExpense.StartDate = updateRecurringExpenseServiceRequestData.StartDate
+	recurringExpense.EndDate = updateRecurringExpenseServiceRequestData.EndDate
+	recurringExpense.UpdatedAt = time.Now().UTC()
+	// Occurrences created so far are kept; the schedule continues after the last one.
+	recurringExpense.NextOccurrence = nextOccurrence(recurringExpense, recurringExpense.LastOccurrence)
+
+	update := map[string]interface{}{"$set": recurringExpense}
+	result, err = s.recurringRepo.Update(context.Background(), filter, update)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRecurringExpenseResponse",
+			updateRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"UpdateRecurringExpenseResponse",
+		updateRecurringExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type removeRecurringExpenseServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	RecurringId	string	`json:"recurringId"`
+}
+
+type removeRecurringExpenseServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleRemoveRecurringExpense(data []byte, replyTo string, correlationId string) {
+	removeRecurringExpenseServiceRequestData := removeRecurringExpenseServiceRequest{}
+	err := json.Unmarshal(data, &removeRecurringExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveRecurringExpenseResponse",
+			removeRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":      removeRecurringExpenseServiceRequestData.UserId,
+		"recurringId": removeRecurringExpenseServiceRequestData.RecurringId,
+	}
+	result, err := s.recurringRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveRecurringExpenseResponse",
+			removeRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveRecurringExpenseResponse",
+			removeRecurringExpenseServiceResponse{
+				Message: "Recurring expense not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	// Expenses that were already created from the definition are kept.
+	result, err = s.recurringRepo.Delete(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveRecurringExpenseResponse",
+			removeRecurringExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RemoveRecurringExpenseResponse",
+		removeRecurringExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/money"
+	"log"
+	"sort"
+	"time"
+)
+
+// reportPeriod returns the bounds [start, end) of the day, week or month that
+// contains the given date.
+func reportPeriod(period string, date time.Time) (time.Time, time.Time) {
+	switch period {
+	case "day":
+		start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	case "week":
+		return budgetPeriod("weekly", date)
+	}
+	return budgetPeriod("monthly", date)
+}
+
+// reportPipeline sums the amounts per category, day and currency in the
+// database. The sums are converted into the base currency afterwards at the
+// rate of their day, so only one document per group and day is read.
+func reportPipeline(filter map[string]interface{}) []interface{} {
+	return []interface{}{
+		map[string]interface{}{"$match": filter},
+		map[string]interface{}{"$group": map[string]interface{}{
+			"_id": map[string]interface{}{
+				"category": "$category",
+				"currency": "$currency",
+				"day": map[string]interface{}{"$dateToString": map[string]interface{}{
+					"format": "%Y-%m-%d",
+					"date":   map[string]interface{}{"$ifNull": []interface{}{"$date", "$createdAt"}},
+				}},
+				// Legacy floating point amounts are summed apart from minor units.
+				"legacy": map[string]interface{}{"$eq": []interface{}{map[string]interface{}{"$type": "$amount"}, "double"}},
+			},
+			"amount": map[string]interface{}{"$sum": "$amount"},
+			"count":  map[string]interface{}{"$sum": 1},
+		}},
+		map[string]interface{}{"$project": map[string]interface{}{
+			"_id":      0,
+			"category": "$_id.category",
+			"currency": "$_id.currency",
+			"day":      "$_id.day",
+			"amount":   1,
+			"count":    1,
+		}},
+	}
+}
+
+type reportGroupServiceResponse struct {
+	Category	string		`json:"category,omitempty"`
+	PeriodStart	*time.Time	`json:"periodStart,omitempty"`
+	PeriodEnd	*time.Time	`json:"periodEnd,omitempty"`
+	Total		string		`json:"total"`
+	Count		int64		`json:"count"`
+	Average		string		`json:"average"`
+}
+
+type getReportServiceRequest struct {
+	Action			string		`json:"action"`
+	UserId			string		`json:"userId"`
+	From			*time.Time	`json:"from"`
+	To				*time.Time	`json:"to"`
+	Type			string		`json:"type"`
+	GroupByCategory	bool		`json:"groupByCategory"`
+	Period			string		`json:"period"`
+	BaseCurrency	string		`json:"baseCurrency"`
+}
+
+type getReportServiceResponse struct {
+	Message			string							`json:"message"`
+	Success			bool							`json:"success"`
+	BaseCurrency	string							`json:"baseCurrency"`
+	Groups			[]reportGroupServiceResponse	`json:"groups"`
+	Total			string							`json:"total"`
+	Count			int64							`json:"count"`
+	Average			string							`json:"average"`
+	MissingRates	bool							`json:"missingRates"`
+}
+
+func averageAmount(total int64, count int64) int64 {
+	if count == 0 {
+		return 0
+	}
+	return total / count
+}
+
+func (s *ExpenseService) HandleGetReport(data []byte, replyTo string, correlationId string) {
+	getReportServiceRequestData := getReportServiceRequest{}
+	err := json.Unmarshal(data, &getReportServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetReportResponse",
+			getReportServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":    getReportServiceRequestData.UserId,
+		"type":      expenseTypeFilter(),
+		"deletedAt": notDeleted(),
+	}
+	if getReportServiceRequestData.Type == "income" {
+		filter["type"] = "income"
+	}
+	if getReportServiceRequestData.From != nil || getReportServiceRequestData.To != nil {
+		dateRange := map[string]interface{}{}
+		if getReportServiceRequestData.From != nil {
+			dateRange["$gte"] = *getReportServiceRequestData.From
+		}
+		if getReportServiceRequestData.To != nil {
+			dateRange["$lt"] = *getReportServiceRequestData.To
+		}
+		filter["date"] = dateRange
+	}
+	result, err := s.mongoDBRepo.Aggregate(context.Background(), reportPipeline(filter))
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetReportResponse",
+			getReportServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	baseCurrency := getReportServiceRequestData.BaseCurrency
+	if !money.IsCurrency(baseCurrency) {
+		baseCurrency = money.DefaultCurrency()
+	}
+
+	type groupKey struct {
+		category	string
+		start		time.Time
+	}
+	type groupSum struct {
+		end		time.Time
+		total	int64
+		count	int64
+	}
+	groups := make(map[groupKey]*groupSum)
+	total, count := int64(0), int64(0)
+	complete := true
+	for _, document := range result.Data {
+		amount, currency := documentAmount(document)
+		day, _ := document["day"].(string)
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			complete = false
+			continue
+		}
+		converted, _, err := s.rates.Convert(amount, currency, baseCurrency, date)
+		if err != nil {
+			complete = false
+			continue
+		}
+		var entries int64
+		switch value := document["count"].(type) {
+		case int32:
+			entries = int64(value)
+		case int64:
+			entries = value
+		}
+
+		key := groupKey{}
+		var end time.Time
+		if getReportServiceRequestData.GroupByCategory {
+			key.category, _ = document["category"].(string)
+		}
+		if getReportServiceRequestData.Period != "" {
+			key.start, end = reportPeriod(getReportServiceRequestData.Period, date)
+		}
+		group, exists := groups[key]
+		if !exists {
+			group = &groupSum{end: end}
+			groups[key] = group
+		}
+		group.total += converted
+		group.count += entries
+		total += converted
+		count += entries
+	}
+
+	keys := make([]groupKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	// Periods are listed in order; within a period the largest categories come first.
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].start.Equal(keys[j].start) {
+			return keys[i].start.Before(keys[j].start)
+		}
+		if groups[keys[i]].total != groups[keys[j]].total {
+			return groups[keys[i]].total > groups[keys[j]].total
+		}
+		return keys[i].category < keys[j].category
+	})
+
+	reportGroups := make([]reportGroupServiceResponse, 0, len(keys))
+	if getReportServiceRequestData.GroupByCategory || getReportServiceRequestData.Period != "" {
+		for _, key := range keys {
+			group := groups[key]
+			reportGroup := reportGroupServiceResponse{
+				Category: key.category,
+				Total:    money.Format(group.total, baseCurrency),
+				Count:    group.count,
+				Average:  money.Format(averageAmount(group.total, group.count), baseCurrency),
+			}
+			if getReportServiceRequestData.Period != "" {
+				start, end := key.start, group.end
+				reportGroup.PeriodStart = &start
+				reportGroup.PeriodEnd = &end
+			}
+			reportGroups = append(reportGroups, reportGroup)
+		}
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetReportResponse",
+		getReportServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			BaseCurrency: baseCurrency,
+			Groups: reportGroups,
+			Total: money.Format(total, baseCurrency),
+			Count: count,
+			Average: money.Format(averageAmount(total, count), baseCurrency),
+			MissingRates: !complete,
+		},
+	)
+}
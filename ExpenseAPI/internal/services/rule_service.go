@@ -0,0 +1,712 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense/internal/models"
+	"expense/internal/money"
+	"expense/internal/repositories"
+	"expense/internal/rules"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errInvalidRule = errors.New("rule is invalid")
+
+// ruleDefinition holds the fields shared by new and updated rules.
+type ruleDefinition struct {
+	Name		string		`json:"name"`
+	Priority	int			`json:"priority"`
+	Field		string		`json:"field"`
+	MatchType	string		`json:"matchType"`
+	Pattern		string		`json:"pattern"`
+	Currency	string		`json:"currency"`
+	MinAmount	json.Number	`json:"minAmount"`
+	MaxAmount	json.Number	`json:"maxAmount"`
+	Category	string		`json:"category"`
+	Tags		[]string	`json:"tags"`
+}
+
+func parseRuleAmount(amount json.Number, currency string) (*int64, error) {
+	if amount == "" {
+		return nil, nil
+	}
+	minor, err := money.Parse(amount.String(), currency)
+	if err != nil {
+		return nil, errInvalidRule
+	}
+	return &minor, nil
+}
+
+// buildRule validates a rule definition against the user's categories.
+func (s *ExpenseService) buildRule(ctx context.Context, userId string, definition ruleDefinition) (models.Rule, error) {
+	rule := models.Rule{
+		UserId:    userId,
+		Name:      definition.Name,
+		Priority:  definition.Priority,
+		Field:     definition.Field,
+		MatchType: definition.MatchType,
+		Pattern:   definition.Pattern,
+		Currency:  definition.Currency,
+		Tags:      definition.Tags,
+	}
+	if rule.Tags == nil {
+		rule.Tags = []string{}
+	}
+
+	var err error
+	rule.MinAmount, err = parseRuleAmount(definition.MinAmount, definition.Currency)
+	if err != nil {
+		return rule, err
+	}
+	rule.MaxAmount, err = parseRuleAmount(definition.MaxAmount, definition.Currency)
+	if err != nil {
+		return rule, err
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return rule, errInvalidRule
+	}
+	if _, err := rules.Compile(rule); err != nil {
+		return rule, errInvalidRule
+	}
+
+	rule.Category, err = s.resolveEntryCategory(ctx, userId, definition.Category)
+	if err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+func (s *ExpenseService) userRules(ctx context.Context, userId string) ([]*rules.Matcher, error) {
+	result, err := s.ruleRepo.Find(ctx, map[string]interface{}{"userId": userId})
+	if !result.Success {
+		return nil, err
+	}
+	userRules := make([]models.Rule, 0, len(result.Data))
+	for _, document := range result.Data {
+		rule := models.Rule{}
+		if err := decodeDocument(document, &rule); err != nil {
+			return nil, err
+		}
+		userRules = append(userRules, rule)
+	}
+	return rules.CompileAll(userRules), nil
+}
+
+// applyRules fills the category and the tags of an entry from the first
+// matching rules that set them. With overwrite, the values of the entry are
+// replaced instead of only being filled in when missing.
+func applyRules(matchers []*rules.Matcher, entry rules.Entry, category string, tags []string, overwrite bool) (string, []string) {
+	categorySet := category != "" && !overwrite
+	tagsSet := len(tags) != 0 && !overwrite
+	for _, matcher := range matchers {
+		if categorySet && tagsSet {
+			break
+		}
+		if !matcher.Match(entry) {
+			continue
+		}
+		rule := matcher.Rule()
+		if !categorySet && rule.Category != "" {
+			category = rule.Category
+			categorySet = true
+		}
+		if !tagsSet && len(rule.Tags) != 0 {
+			tags = rule.Tags
+			tagsSet = true
+		}
+	}
+	return category, tags
+}
+
+// fillFromRules applies the user's rules to a new entry without a category or tags.
+func (s *ExpenseService) fillFromRules(ctx context.Context, userId string, entry rules.Entry, category string, tags []string) (string, []string, error) {
+	if category != "" && len(tags) != 0 {
+		return category, tags, nil
+	}
+	matchers, err := s.userRules(ctx, userId)
+	if err != nil {
+		return category, tags, err
+	}
+	category, tags = applyRules(matchers, entry, category, tags, false)
+	return category, tags, nil
+}
+
+type getRuleServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	RuleId	string	`json:"ruleId"`
+}
+
+type getRuleServiceResponse struct {
+	Message	string						`json:"message"`
+	Success	bool						`json:"success"`
+	Rules	[]map[string]interface{}	`json:"rules"`
+}
+
+func (s *ExpenseService) HandleGetRule(data []byte, replyTo string, correlationId string) {
+	getRuleServiceRequestData := getRuleServiceRequest{}
+	err := json.Unmarshal(data, &getRuleServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetRuleResponse",
+			getRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": getRuleServiceRequestData.UserId,
+	}
+	if getRuleServiceRequestData.RuleId != "" {
+		filter["ruleId"] = getRuleServiceRequestData.RuleId
+	}
+	result, err := s.ruleRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetRuleResponse",
+			getRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	userRules := result.Data
+	for _, rule := range userRules {
+		currency, _ := rule["currency"].(string)
+		for _, key := range []string{"minAmount", "maxAmount"} {
+			if amount, ok := rule[key].(int64); ok {
+				rule[key] = money.Format(amount, currency)
+			}
+		}
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetRuleResponse",
+		getRuleServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Rules: userRules,
+		},
+	)
+}
+
+type addRuleServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	ruleDefinition
+}
+
+type addRuleServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+	RuleId	string	`json:"ruleId"`
+}
+
+func (s *ExpenseService) HandleAddRule(data []byte, replyTo string, correlationId string) {
+	addRuleServiceRequestData := addRuleServiceRequest{}
+	err := json.Unmarshal(data, &addRuleServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRuleResponse",
+			addRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	rule, err := s.buildRule(context.Background(), addRuleServiceRequestData.UserId, addRuleServiceRequestData.ruleDefinition)
+	if err == errUnknownCategory {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRuleResponse",
+			addRuleServiceResponse{
+				Message: "Category is unknown!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err == errInvalidRule {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRuleResponse",
+			addRuleServiceResponse{
+				Message: "Rule is invalid!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRuleResponse",
+			addRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	now := time.Now().UTC()
+	rule.RuleId = uuid.New().String()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	result, err := s.ruleRepo.Insert(context.Background(), rule)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"AddRuleResponse",
+			addRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"AddRuleResponse",
+		addRuleServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			RuleId: rule.RuleId,
+		},
+	)
+}
+
+type updateRuleServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	RuleId	string	`json:"ruleId"`
+	ruleDefinition
+}
+
+type updateRuleServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleUpdateRule(data []byte, replyTo string, correlationId string) {
+	updateRuleServiceRequestData := updateRuleServiceRequest{}
+	err := json.Unmarshal(data, &updateRuleServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRuleResponse",
+			updateRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": updateRuleServiceRequestData.UserId,
+		"ruleId": updateRuleServiceRequestData.RuleId,
+	}
+	result, err := s.ruleRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRuleResponse",
+			updateRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRuleResponse",
+			updateRuleServiceResponse{
+				Message: "Rule not found!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	rule, err := s.buildRule(context.Background(), updateRuleServiceRequestData.UserId, updateRuleServiceRequestData.ruleDefinition)
+	if err == errUnknownCategory {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRuleResponse",
+			updateRuleServiceResponse{
+				Message: "Category is unknown!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err == errInvalidRule {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRuleResponse",
+			updateRuleServiceResponse{
+				Message: "Rule is invalid!",
+				Success: false,
+				Status: http.StatusBadRequest,
+			},
+		)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRuleResponse",
+			updateRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{
+			"name":      rule.Name,
+			"priority":  rule.Priority,
+			"field":     rule.Field,
+			"matchType": rule.MatchType,
+			"pattern":   rule.Pattern,
+			"currency":  rule.Currency,
+			"minAmount": rule.MinAmount,
+			"maxAmount": rule.MaxAmount,
+			"category":  rule.Category,
+			"tags":      rule.Tags,
+			"updatedAt": time.Now().UTC(),
+		},
+	}
+	result, err = s.ruleRepo.Update(context.Background(), filter, update)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"UpdateRuleResponse",
+			updateRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"UpdateRuleResponse",
+		updateRuleServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type removeRuleServiceRequest struct {
+	Action	string	`json:"action"`
+	UserId	string	`json:"userId"`
+	RuleId	string	`json:"ruleId"`
+}
+
+type removeRuleServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleRemoveRule(data []byte, replyTo string, correlationId string) {
+	removeRuleServiceRequestData := removeRuleServiceRequest{}
+	err := json.Unmarshal(data, &removeRuleServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveRuleResponse",
+			removeRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": removeRuleServiceRequestData.UserId,
+		"ruleId": removeRuleServiceRequestData.RuleId,
+	}
+	result, err := s.ruleRepo.Delete(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RemoveRuleResponse",
+			removeRuleServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RemoveRuleResponse",
+		removeRuleServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
+
+type applyRulesServiceRequest struct {
+	Action		string		`json:"action"`
+	UserId		string		`json:"userId"`
+	From		*time.Time	`json:"from"`
+	To			*time.Time	`json:"to"`
+	Overwrite	bool		`json:"overwrite"`
+}
+
+type applyRulesServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Matched	int		`json:"matched"`
+	Updated	int		`json:"updated"`
+	Skipped	int		`json:"skipped"`
+}
+
+// HandleApplyRules re-runs the user's rules over the stored entries. Without
+// overwrite only missing categories and tags are filled in.
+func (s *ExpenseService) HandleApplyRules(data []byte, replyTo string, correlationId string) {
+	applyRulesServiceRequestData := applyRulesServiceRequest{}
+	err := json.Unmarshal(data, &applyRulesServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"ApplyRulesResponse",
+			applyRulesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	ctx := context.Background()
+	matchers, err := s.userRules(ctx, applyRulesServiceRequestData.UserId)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"ApplyRulesResponse",
+			applyRulesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": applyRulesServiceRequestData.UserId,
+		"deletedAt": notDeleted(),
+	}
+	dateFilter := map[string]interface{}{}
+	if applyRulesServiceRequestData.From != nil {
+		dateFilter["$gte"] = *applyRulesServiceRequestData.From
+	}
+	if applyRulesServiceRequestData.To != nil {
+		dateFilter["$lt"] = *applyRulesServiceRequestData.To
+	}
+	if len(dateFilter) != 0 {
+		filter["date"] = dateFilter
+	}
+	result, err := s.mongoDBRepo.Find(ctx, filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"ApplyRulesResponse",
+			applyRulesServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	matched, updated, skipped := 0, 0, 0
+	for _, document := range result.Data {
+		// Legacy entries still hold float amounts, which the model cannot decode.
+		amount, currency := documentAmount(document)
+		expense := models.Expense{}
+		if err := decodeDocument(patchedDocument(document, map[string]interface{}{"amount": amount, "currency": currency}), &expense); err != nil {
+			log.Println(err)
+			skipped++
+			continue
+		}
+
+		entry := rules.Entry{
+			Payee:       expense.Payee,
+			Description: expense.Description,
+			Amount:      expense.Amount,
+			Currency:    expense.Currency,
+		}
+		if _, ok := rules.First(matchers, entry); !ok {
+			continue
+		}
+		matched++
+
+		category, tags := applyRules(matchers, entry, expense.Category, expense.Tags, applyRulesServiceRequestData.Overwrite)
+		if category == expense.Category && strings.Join(tags, ",") == strings.Join(expense.Tags, ",") {
+			continue
+		}
+		version := documentVersion(document)
+		fields := map[string]interface{}{
+			"category":  category,
+			"tags":      tags,
+			"updatedAt": time.Now().UTC(),
+			"version":   version + 1,
+		}
+		update := map[string]interface{}{
+			"$set": fields,
+		}
+		expenseFilter := map[string]interface{}{
+			"userId":    expense.UserId,
+			"expenseId": expense.ExpenseId,
+			"version":   versionFilter(version),
+		}
+		// Entries changed since they were read are left to the next run.
+		result, err := s.mongoDBRepo.UpdateIfMatch(ctx, expenseFilter, update)
+		if err == repositories.ErrNoMatch {
+			skipped++
+			continue
+		}
+		if !result.Success {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"ApplyRulesResponse",
+				applyRulesServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+		err = s.recordHistory(ctx, newExpenseHistory(expense.UserId, expense.ExpenseId, applyRulesServiceRequestData.UserId, "update", document, patchedDocument(document, fields)))
+		if err != nil {
+			log.Println(err)
+			SendResponse(
+				s.channel,
+				replyTo,
+				correlationId,
+				"ApplyRulesResponse",
+				applyRulesServiceResponse{
+					Message: "An error occured!",
+					Success: false,
+				},
+			)
+			return
+		}
+		updated++
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"ApplyRulesResponse",
+		applyRulesServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Matched: matched,
+			Updated: updated,
+			Skipped: skipped,
+		},
+	)
+}
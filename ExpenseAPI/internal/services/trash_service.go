@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expense/internal/money"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// trashRetention is how long removed entries stay in the trash before they are
+// purged, read from TRASH_RETENTION as a duration such as "720h".
+func trashRetention() time.Duration {
+	retention, err := time.ParseDuration(strings.TrimSpace(os.Getenv("TRASH_RETENTION")))
+	if err != nil || retention <= 0 {
+		return defaultTrashRetention
+	}
+	return retention
+}
+
+// purgeTrash deletes the entries whose retention has passed for good, together
+// with their receipts. It runs on the scheduler's ticks.
+func (s *ExpenseService) purgeTrash() {
+	ctx := context.Background()
+	filter := map[string]interface{}{
+		"deletedAt": map[string]interface{}{"$lte": time.Now().UTC().Add(-trashRetention())},
+	}
+	result, err := s.mongoDBRepo.Find(ctx, filter)
+	if !result.Success {
+		log.Println(err)
+		return
+	}
+
+	for _, expense := range result.Data {
+		userId, _ := expense["userId"].(string)
+		expenseId, _ := expense["expenseId"].(string)
+		// The receipts go first, so that a failure leaves the entry to be retried on the next tick.
+		if err := s.removeExpenseReceipts(ctx, userId, expenseId); err != nil {
+			log.Println(err)
+			continue
+		}
+		entryFilter := map[string]interface{}{
+			"userId":    userId,
+			"expenseId": expenseId,
+			"deletedAt": map[string]interface{}{"$exists": true},
+		}
+		result, err := s.mongoDBRepo.Delete(ctx, entryFilter)
+		if !result.Success {
+			log.Println(err)
+			continue
+		}
+		// The history outlives the entry, e.g. for disputes after the retention.
+		if err := s.recordHistory(ctx, newExpenseHistory(userId, expenseId, "", "purge", nil, nil)); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+type getTrashServiceRequest struct {
+	Action			string	`json:"action"`
+	UserId			string	`json:"userId"`
+	BaseCurrency	string	`json:"baseCurrency"`
+}
+
+type getTrashServiceResponse struct {
+	Message		string						`json:"message"`
+	Success		bool						`json:"success"`
+	Expenses	[]map[string]interface{}	`json:"expenses"`
+}
+
+func (s *ExpenseService) HandleGetTrash(data []byte, replyTo string, correlationId string) {
+	getTrashServiceRequestData := getTrashServiceRequest{}
+	err := json.Unmarshal(data, &getTrashServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetTrashResponse",
+			getTrashServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":    getTrashServiceRequestData.UserId,
+		"deletedAt": map[string]interface{}{"$exists": true},
+	}
+	result, err := s.mongoDBRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetTrashResponse",
+			getTrashServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	baseCurrency := getTrashServiceRequestData.BaseCurrency
+	if !money.IsCurrency(baseCurrency) {
+		baseCurrency = money.DefaultCurrency()
+	}
+	expenses := result.Data
+	if expenses == nil {
+		expenses = []map[string]interface{}{}
+	}
+	s.convertExpenses(expenses, baseCurrency)
+	retention := trashRetention()
+	for _, expense := range expenses {
+		formatAmount(expense, baseCurrency)
+		expense["purgeAt"] = documentTime(expense["deletedAt"]).Add(retention)
+	}
+	sort.SliceStable(expenses, func(i, j int) bool {
+		return documentTime(expenses[i]["deletedAt"]).After(documentTime(expenses[j]["deletedAt"]))
+	})
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetTrashResponse",
+		getTrashServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Expenses: expenses,
+		},
+	)
+}
+
+type restoreExpenseServiceRequest struct {
+	Action		string	`json:"action"`
+	UserId		string	`json:"userId"`
+	ExpenseId	string	`json:"expenseId"`
+}
+
+type restoreExpenseServiceResponse struct {
+	Message	string	`json:"message"`
+	Success	bool	`json:"success"`
+	Status	int		`json:"status,omitempty"`
+}
+
+func (s *ExpenseService) HandleRestoreExpense(data []byte, replyTo string, correlationId string) {
+	restoreExpenseServiceRequestData := restoreExpenseServiceRequest{}
+	err := json.Unmarshal(data, &restoreExpenseServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RestoreExpenseResponse",
+			restoreExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId":    restoreExpenseServiceRequestData.UserId,
+		"expenseId": restoreExpenseServiceRequestData.ExpenseId,
+		"deletedAt": map[string]interface{}{"$exists": true},
+	}
+	result, err := s.mongoDBRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RestoreExpenseResponse",
+			restoreExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+	if len(result.Data) == 0 {
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RestoreExpenseResponse",
+			restoreExpenseServiceResponse{
+				Message: "Expense not found in the trash!",
+				Success: false,
+				Status: http.StatusNotFound,
+			},
+		)
+		return
+	}
+
+	restored := result.Data[0]
+	delete(restored, "deletedAt")
+	restored["version"] = documentVersion(restored) + 1
+
+	update := map[string]interface{}{
+		"$unset": map[string]interface{}{"deletedAt": ""},
+		"$set":   map[string]interface{}{"version": restored["version"]},
+	}
+	result, err = s.mongoDBRepo.Update(context.Background(), filter, update)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RestoreExpenseResponse",
+			restoreExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	userId := restoreExpenseServiceRequestData.UserId
+	err = s.recordHistory(context.Background(), newExpenseHistory(userId, restoreExpenseServiceRequestData.ExpenseId, userId, "restore", nil, restored))
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"RestoreExpenseResponse",
+			restoreExpenseServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"RestoreExpenseResponse",
+		restoreExpenseServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+		},
+	)
+}
@@ -2,9 +2,15 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
+	"expense/internal/money"
 	"log"
+	"net/http"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type response struct {
@@ -20,7 +26,7 @@ func SendResponse(ch *amqp.Channel, replyTo string, correlationID string, action
 
     responseDataJSON, err := json.Marshal(responseData)
     if err != nil {
-        log.Println("Failed to encode response data to JSON: %v", err)
+        log.Printf("Failed to encode response data to JSON: %v", err)
         return
     }
 
@@ -39,6 +45,195 @@ func SendResponse(ch *amqp.Channel, replyTo string, correlationID string, action
         },
     )
     if err != nil {
-        log.Println("Failed to publish a response message: %v", err)
+        log.Printf("Failed to publish a response message: %v", err)
     }
-}
\ No newline at end of file
+}
+
+type serviceResponse struct {
+    Action string                 `json:"action"`
+    Data   map[string]interface{} `json:"data"`
+}
+
+// SendRequest publishes a request to another service's queue and waits for its reply.
+func SendRequest(ch *amqp.Channel, queueName string, action string, data interface{}, timeout time.Duration) (map[string]interface{}, error) {
+    dataJSON, err := json.Marshal(data)
+    if err != nil {
+        return nil, err
+    }
+
+    replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    correlationId := uuid.New().String()
+    messages, err := ch.Consume(replyQueue.Name, correlationId, true, false, false, false, nil)
+    if err != nil {
+        return nil, err
+    }
+    defer ch.Cancel(correlationId, false)
+
+    err = ch.Publish(
+        "",
+        queueName,
+        false,
+        false,
+        amqp.Publishing{
+            ContentType: "application/json",
+            ReplyTo: replyQueue.Name,
+            CorrelationId: correlationId,
+            Body: dataJSON,
+            Headers: amqp.Table{
+                "action": action,
+            },
+        },
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    timer := time.NewTimer(timeout)
+    defer timer.Stop()
+    for {
+        select {
+        case message, ok := <-messages:
+            if !ok {
+                return nil, errors.New("reply queue is closed")
+            }
+            if message.CorrelationId != correlationId {
+                continue
+            }
+            serviceResponseData := serviceResponse{}
+            if err := json.Unmarshal(message.Body, &serviceResponseData); err != nil {
+                return nil, err
+            }
+            return serviceResponseData.Data, nil
+        case <-timer.C:
+            return nil, errors.New("no response is received")
+        }
+    }
+}
+
+// documentAmount reads the minor units and currency of a stored expense document.
+func documentAmount(expense map[string]interface{}) (int64, string) {
+	currency, _ := expense["currency"].(string)
+	if currency == "" {
+		currency = money.DefaultCurrency()
+	}
+
+	switch amount := expense["amount"].(type) {
+	case int64:
+		return amount, currency
+	case int32:
+		return int64(amount), currency
+	case float64:
+		// Legacy documents that were not migrated yet still hold floats.
+		minor, _ := money.FromFloat(amount, currency)
+		return minor, currency
+	}
+	return 0, currency
+}
+
+// entryType reads the type of a stored entry. Entries stored before types were
+// introduced are expenses.
+func entryType(expense map[string]interface{}) string {
+	if value, _ := expense["type"].(string); value != "" {
+		return value
+	}
+	return "expense"
+}
+
+func entryTypeOrDefault(entryType string) string {
+	if entryType == "" {
+		return "expense"
+	}
+	return entryType
+}
+
+// expenseTypeFilter matches expense entries, including the ones stored without a type.
+func expenseTypeFilter() map[string]interface{} {
+	return map[string]interface{}{"$nin": []string{"income", "transfer"}}
+}
+
+// decodeDocument converts a document returned by the repository into a model.
+func decodeDocument(document map[string]interface{}, target interface{}) error {
+	documentJSON, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(documentJSON, target)
+}
+
+func documentTime(value interface{}) time.Time {
+	switch date := value.(type) {
+	case primitive.DateTime:
+		return date.Time().UTC()
+	case time.Time:
+		return date.UTC()
+	}
+	return time.Time{}
+}
+
+func expenseDate(expense map[string]interface{}) time.Time {
+	if date := documentTime(expense["date"]); !date.IsZero() {
+		return date
+	}
+	if createdAt := documentTime(expense["createdAt"]); !createdAt.IsZero() {
+		return createdAt
+	}
+	return time.Now().UTC()
+}
+
+// formatAmount replaces the stored minor units of an expense document with
+// the decimal string representation returned to clients.
+func formatAmount(expense map[string]interface{}, baseCurrency string) {
+	amount, currency := documentAmount(expense)
+	expense["amount"] = money.Format(amount, currency)
+	expense["currency"] = currency
+
+	if baseAmount, ok := expense["baseAmount"].(int64); ok {
+		expense["baseAmount"] = money.Format(baseAmount, baseCurrency)
+		expense["baseCurrency"] = baseCurrency
+	}
+}
+
+// entryError is a failure of an entry operation that the client can fix, with
+// the HTTP status to report.
+type entryError struct {
+	status	int
+	message	string
+}
+
+func (e *entryError) Error() string {
+	return e.message
+}
+
+// notDeleted matches the entries that are not in the trash.
+func notDeleted() map[string]interface{} {
+	return map[string]interface{}{"$exists": false}
+}
+
+// documentVersion reads the version of a stored entry. Entries stored before
+// versions were kept have none, which counts as version 0.
+func documentVersion(document map[string]interface{}) int64 {
+	switch version := document["version"].(type) {
+	case int64:
+		return version
+	case int32:
+		return int64(version)
+	case float64:
+		return int64(version)
+	}
+	return 0
+}
+
+// versionFilter matches the entries at a version.
+func versionFilter(version int64) interface{} {
+	if version == 0 {
+		return map[string]interface{}{"$in": []interface{}{0, nil}}
+	}
+	return version
+}
+
+// errStaleVersion reports a write based on an outdated read of the entry.
+var errStaleVersion = &entryError{status: http.StatusPreconditionFailed, message: "Expense was changed in the meantime!"}
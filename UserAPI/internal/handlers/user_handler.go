@@ -3,11 +3,41 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/streadway/amqp"
 )
 
+// currencies are the ISO 4217 codes that a base currency can be set to.
+var currencies = map[string]struct{}{
+	"AED": {}, "AFN": {}, "ALL": {}, "AMD": {}, "ANG": {}, "AOA": {}, "ARS": {}, "AUD": {},
+	"AWG": {}, "AZN": {}, "BAM": {}, "BBD": {}, "BDT": {}, "BGN": {}, "BHD": {}, "BIF": {},
+	"BMD": {}, "BND": {}, "BOB": {}, "BRL": {}, "BSD": {}, "BTN": {}, "BWP": {}, "BYN": {},
+	"BZD": {}, "CAD": {}, "CDF": {}, "CHF": {}, "CLF": {}, "CLP": {}, "CNY": {}, "COP": {},
+	"CRC": {}, "CUP": {}, "CVE": {}, "CZK": {}, "DJF": {}, "DKK": {}, "DOP": {}, "DZD": {},
+	"EGP": {}, "ERN": {}, "ETB": {}, "EUR": {}, "FJD": {}, "FKP": {}, "GBP": {}, "GEL": {},
+	"GHS": {}, "GIP": {}, "GMD": {}, "GNF": {}, "GTQ": {}, "GYD": {}, "HKD": {}, "HNL": {},
+	"HTG": {}, "HUF": {}, "IDR": {}, "ILS": {}, "INR": {}, "IQD": {}, "IRR": {}, "ISK": {},
+	"JMD": {}, "JOD": {}, "JPY": {}, "KES": {}, "KGS": {}, "KHR": {}, "KMF": {}, "KPW": {},
+	"KRW": {}, "KWD": {}, "KYD": {}, "KZT": {}, "LAK": {}, "LBP": {}, "LKR": {}, "LRD": {},
+	"LSL": {}, "LYD": {}, "MAD": {}, "MDL": {}, "MGA": {}, "MKD": {}, "MMK": {}, "MNT": {},
+	"MOP": {}, "MRU": {}, "MUR": {}, "MVR": {}, "MWK": {}, "MXN": {}, "MYR": {}, "MZN": {},
+	"NAD": {}, "NGN": {}, "NIO": {}, "NOK": {}, "NPR": {}, "NZD": {}, "OMR": {}, "PAB": {},
+	"PEN": {}, "PGK": {}, "PHP": {}, "PKR": {}, "PLN": {}, "PYG": {}, "QAR": {}, "RON": {},
+	"RSD": {}, "RUB": {}, "RWF": {}, "SAR": {}, "SBD": {}, "SCR": {}, "SDG": {}, "SEK": {},
+	"SGD": {}, "SHP": {}, "SLE": {}, "SOS": {}, "SRD": {}, "SSP": {}, "STN": {}, "SVC": {},
+	"SYP": {}, "SZL": {}, "THB": {}, "TJS": {}, "TMT": {}, "TND": {}, "TOP": {}, "TRY": {},
+	"TTD": {}, "TWD": {}, "TZS": {}, "UAH": {}, "UGX": {}, "USD": {}, "UYI": {}, "UYU": {},
+	"UYW": {}, "UZS": {}, "VES": {}, "VND": {}, "VUV": {}, "WST": {}, "XAF": {}, "XCD": {},
+	"XOF": {}, "XPF": {}, "YER": {}, "ZAR": {}, "ZMW": {}, "ZWG": {},
+}
+
+func isCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
 type loginWebRequest struct {
 	Action   string `json:"action"`
 	Email	 string `json:"email"`
@@ -104,6 +134,7 @@ type registerWebRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Name 	 string `json:"name"`
+	BaseCurrency string `json:"baseCurrency"`
 }
 
 type registerWebResponse struct {
@@ -125,6 +156,12 @@ func HandleRegisterRoute(ch *amqp.Channel) func(http.ResponseWriter, *http.Reque
             return
         }
 
+		registerWebRequestData.BaseCurrency = strings.ToUpper(registerWebRequestData.BaseCurrency)
+		if registerWebRequestData.BaseCurrency != "" && !isCurrency(registerWebRequestData.BaseCurrency) {
+			http.Error(w, "\"BaseCurrency\" must be an ISO 4217 currency code!", http.StatusBadRequest)
+			return
+		}
+
         correlationId := uuid.New().String()
 
         replyQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
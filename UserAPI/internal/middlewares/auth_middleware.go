@@ -21,6 +21,9 @@ func CreateToken(user models.User) (string, error) {
 		"userId":         	user.UserId,
 		"expirationDate":  	time.Now().Add(tokenExpiration).Unix(),
 	}
+	if user.BaseCurrency != "" {
+		claims["baseCurrency"] = user.BaseCurrency
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(jwtSecret))
@@ -5,4 +5,5 @@ type User struct {
 	Email		string	`json:"email" bson:"email"`
 	Password	string	`json:"password" bson:"password"`
 	Name		string	`json:"name" bson:"name"`
+	BaseCurrency	string	`json:"baseCurrency" bson:"baseCurrency"`
 }
\ No newline at end of file
@@ -77,6 +77,8 @@ func (s *UserService) Start() {
 				s.HandleLogin(message.Body, message.ReplyTo, message.CorrelationId)
 			case "Register":
 				s.HandleRegister(message.Body, message.ReplyTo, message.CorrelationId)
+			case "GetUsers":
+				s.HandleGetUsers(message.Body, message.ReplyTo, message.CorrelationId)
 			default:
 				log.Printf("Action (%s) is unknown!", action)
 		}
@@ -224,6 +226,7 @@ type registerServiceRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Name 	 string `json:"name"`
+	BaseCurrency string `json:"baseCurrency"`
 }
 
 type registerServiceResponse struct {
@@ -231,6 +234,10 @@ type registerServiceResponse struct {
 	Success bool 	`json:"success"`
 }
 
+type seedCategoriesEvent struct {
+	UserId	string	`json:"userId"`
+}
+
 func (s *UserService) HandleRegister(data []byte, replyTo string, correlationId string) {	
 	registerServiceRequestData := registerServiceRequest{}
 	err := json.Unmarshal(data, &registerServiceRequestData)
@@ -303,6 +310,7 @@ func (s *UserService) HandleRegister(data []byte, replyTo string, correlationId
 		Email: registerServiceRequestData.Email,
 		Password: string(hashedPasswordBytes),
 		Name: registerServiceRequestData.Name,
+		BaseCurrency: registerServiceRequestData.BaseCurrency,
 	}
 	insertionResult, err := s.mongoDBRepo.Insert(context.Background(), newUser)
 	if !insertionResult.Success {
@@ -320,6 +328,9 @@ func (s *UserService) HandleRegister(data []byte, replyTo string, correlationId
 		return
 	}
 
+	// The expense service seeds the default expense categories for the new user.
+	SendEvent(s.channel, "expenseQueue", "SeedCategories", seedCategoriesEvent{UserId: userId})
+
 	SendResponse(
 		s.channel, 
 		replyTo, 
@@ -331,3 +342,83 @@ func (s *UserService) HandleRegister(data []byte, replyTo string, correlationId
 		},
 	)
 }
+
+type getUsersServiceRequest struct {
+	Action	string		`json:"action"`
+	UserIds	[]string	`json:"userIds"`
+}
+
+type userSummary struct {
+	UserId	string	`json:"userId"`
+	Email	string	`json:"email"`
+	Name	string	`json:"name"`
+}
+
+type getUsersServiceResponse struct {
+	Message	string			`json:"message"`
+	Success	bool			`json:"success"`
+	Users	[]userSummary	`json:"users"`
+}
+
+// HandleGetUsers lets other services look up users by id. Unknown ids are left out of the result.
+func (s *UserService) HandleGetUsers(data []byte, replyTo string, correlationId string) {
+	getUsersServiceRequestData := getUsersServiceRequest{}
+	err := json.Unmarshal(data, &getUsersServiceRequestData)
+	if err != nil {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetUsersResponse",
+			getUsersServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	filter := map[string]interface{}{
+		"userId": map[string]interface{}{"$in": getUsersServiceRequestData.UserIds},
+	}
+	result, err := s.mongoDBRepo.Find(context.Background(), filter)
+	if !result.Success {
+		log.Println(err)
+		SendResponse(
+			s.channel,
+			replyTo,
+			correlationId,
+			"GetUsersResponse",
+			getUsersServiceResponse{
+				Message: "An error occured!",
+				Success: false,
+			},
+		)
+		return
+	}
+
+	users := make([]userSummary, 0, len(result.Data))
+	for _, eachData := range result.Data {
+		userId, _ := eachData["userId"].(string)
+		email, _ := eachData["email"].(string)
+		name, _ := eachData["name"].(string)
+		users = append(users, userSummary{
+			UserId: userId,
+			Email: email,
+			Name: name,
+		})
+	}
+
+	SendResponse(
+		s.channel,
+		replyTo,
+		correlationId,
+		"GetUsersResponse",
+		getUsersServiceResponse{
+			Message: "Operation is successful!",
+			Success: true,
+			Users: users,
+		},
+	)
+}
@@ -20,7 +20,7 @@ func SendResponse(ch *amqp.Channel, replyTo string, correlationID string, action
 
     responseDataJSON, err := json.Marshal(responseData)
     if err != nil {
-        log.Println("Failed to encode response data to JSON: %v", err)
+        log.Printf("Failed to encode response data to JSON: %v", err)
         return
     }
 
@@ -39,6 +39,32 @@ func SendResponse(ch *amqp.Channel, replyTo string, correlationID string, action
         },
     )
     if err != nil {
-        log.Println("Failed to publish a response message: %v", err)
+        log.Printf("Failed to publish a response message: %v", err)
     }
-}
\ No newline at end of file
+}
+
+// SendEvent publishes a one-way message to another service's queue.
+func SendEvent(ch *amqp.Channel, queueName string, action string, data interface{}) {
+    dataJSON, err := json.Marshal(data)
+    if err != nil {
+        log.Printf("Failed to encode event data to JSON: %v", err)
+        return
+    }
+
+    err = ch.Publish(
+        "",
+        queueName,
+        false,
+        false,
+        amqp.Publishing{
+            ContentType: "application/json",
+            Body: dataJSON,
+            Headers: amqp.Table{
+                "action": action,
+            },
+        },
+    )
+    if err != nil {
+        log.Printf("Failed to publish an event message: %v", err)
+    }
+}